@@ -0,0 +1,192 @@
+package kbhandler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/disaster37/generic-objectmatcher/patch"
+	jsonIterator "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+)
+
+const (
+	basePathAlertingRule = "/api/alerting/rule" // Base URL to access on Kibana alerting rules
+)
+
+// AlertingRule is the Kibana alerting rule
+// The execution status, the API key owner, the mute state and the audit fields (created_by, updated_at, etc.) returned by Kibana are not decoded
+type AlertingRule struct {
+	ID         string                 `json:"id,omitempty"`
+	Name       string                 `json:"name"`
+	Consumer   string                 `json:"consumer,omitempty"`
+	RuleTypeID string                 `json:"rule_type_id,omitempty"`
+	Schedule   AlertingRuleSchedule   `json:"schedule"`
+	Params     map[string]interface{} `json:"params,omitempty"`
+	Actions    []AlertingRuleAction   `json:"actions,omitempty"`
+	Tags       []string               `json:"tags,omitempty"`
+	Throttle   string                 `json:"throttle,omitempty"`
+	NotifyWhen string                 `json:"notify_when,omitempty"`
+	Enabled    *bool                  `json:"enabled,omitempty"`
+}
+
+// AlertingRuleSchedule is the check interval of the rule
+type AlertingRuleSchedule struct {
+	Interval string `json:"interval"`
+}
+
+// AlertingRuleAction is the action run by the rule on connector
+type AlertingRuleAction struct {
+	Group  string                 `json:"group"`
+	ID     string                 `json:"id"`
+	Params map[string]interface{} `json:"params"`
+}
+
+// alertingRuleUpdateRequest is the body expected by Kibana to update alerting rule
+type alertingRuleUpdateRequest struct {
+	Name       string                 `json:"name"`
+	Schedule   AlertingRuleSchedule   `json:"schedule"`
+	Params     map[string]interface{} `json:"params"`
+	Actions    []AlertingRuleAction   `json:"actions"`
+	Tags       []string               `json:"tags,omitempty"`
+	Throttle   string                 `json:"throttle,omitempty"`
+	NotifyWhen string                 `json:"notify_when,omitempty"`
+}
+
+// AlertingRuleCreate permit to create alerting rule on user space
+// Kibana generate the ID if not provided
+func (h *KibanaHandlerImpl) AlertingRuleCreate(rule *AlertingRule, userSpace string) (err error) {
+	h.log.Debug("Create alerting rule", "scope", "alerting_rule", "name", rule.Name, "space", userSpace)
+	h, span := h.startSpan("AlertingRuleCreate", "alerting_rule", rule.Name, userSpace)
+	defer span.end(&err)
+
+	// Kibana not accept the ID on body
+	payload := *rule
+	payload.ID = ""
+
+	path := userSpacePath(userSpace, basePathAlertingRule)
+	if rule.ID != "" {
+		path = fmt.Sprintf("%s/%s", path, rule.ID)
+	}
+
+	return h.doRequest(http.MethodPost, path, &payload, nil)
+}
+
+// AlertingRuleUpdate permit to update alerting rule on user space
+// It enable or disable the rule if Enabled is provided
+func (h *KibanaHandlerImpl) AlertingRuleUpdate(rule *AlertingRule, userSpace string) (err error) {
+	h.log.Debug("Update alerting rule", "scope", "alerting_rule", "name", rule.ID, "space", userSpace)
+	h, span := h.startSpan("AlertingRuleUpdate", "alerting_rule", rule.ID, userSpace)
+	defer span.end(&err)
+
+	payload := &alertingRuleUpdateRequest{
+		Name:       rule.Name,
+		Schedule:   rule.Schedule,
+		Params:     rule.Params,
+		Actions:    rule.Actions,
+		Tags:       rule.Tags,
+		Throttle:   rule.Throttle,
+		NotifyWhen: rule.NotifyWhen,
+	}
+	if payload.Params == nil {
+		payload.Params = map[string]interface{}{}
+	}
+	if payload.Actions == nil {
+		payload.Actions = []AlertingRuleAction{}
+	}
+
+	path := userSpacePath(userSpace, fmt.Sprintf("%s/%s", basePathAlertingRule, rule.ID))
+	if err = h.doRequest(http.MethodPut, path, payload, nil); err != nil {
+		return err
+	}
+
+	if rule.Enabled != nil {
+		if *rule.Enabled {
+			return h.AlertingRuleEnable(rule.ID, userSpace)
+		}
+		return h.AlertingRuleDisable(rule.ID, userSpace)
+	}
+
+	return nil
+}
+
+// AlertingRuleDelete permit to delete alerting rule on user space
+func (h *KibanaHandlerImpl) AlertingRuleDelete(id, userSpace string) (err error) {
+	h.log.Debug("Delete alerting rule", "scope", "alerting_rule", "name", id, "space", userSpace)
+	h, span := h.startSpan("AlertingRuleDelete", "alerting_rule", id, userSpace)
+	defer span.end(&err)
+
+	path := userSpacePath(userSpace, fmt.Sprintf("%s/%s", basePathAlertingRule, id))
+	return h.doRequest(http.MethodDelete, path, nil, nil)
+}
+
+// AlertingRuleGet permit to get alerting rule on user space
+// It return nil if alerting rule not exist
+func (h *KibanaHandlerImpl) AlertingRuleGet(id, userSpace string) (rule *AlertingRule, err error) {
+	h.log.Debug("Get alerting rule", "scope", "alerting_rule", "name", id, "space", userSpace)
+	h, span := h.startSpan("AlertingRuleGet", "alerting_rule", id, userSpace)
+	defer span.end(&err)
+
+	rule = &AlertingRule{}
+	path := userSpacePath(userSpace, fmt.Sprintf("%s/%s", basePathAlertingRule, id))
+	if err = h.doRequest(http.MethodGet, path, nil, rule); err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return rule, nil
+}
+
+// AlertingRuleDiff permit to diff alerting rule
+func (h *KibanaHandlerImpl) AlertingRuleDiff(actualObject, expectedObject, originalObject *AlertingRule) (patchResult *patch.PatchResult, err error) {
+	defer h.observeDiff("alerting_rule", actualObject == nil, &patchResult)
+
+	// If not yet exist
+	if actualObject == nil {
+		expected, err := jsonIterator.ConfigCompatibleWithStandardLibrary.Marshal(expectedObject)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to convert expected object to byte sequence")
+		}
+
+		return &patch.PatchResult{
+			Patch:    expected,
+			Current:  expected,
+			Modified: expected,
+			Original: nil,
+			Patched:  expectedObject,
+		}, nil
+	}
+
+	return patch.DefaultPatchMaker.Calculate(actualObject, expectedObject, originalObject)
+}
+
+// AlertingRuleEnable permit to enable alerting rule on user space
+func (h *KibanaHandlerImpl) AlertingRuleEnable(id, userSpace string) (err error) {
+	h.log.Debug("Enable alerting rule", "scope", "alerting_rule", "name", id, "space", userSpace)
+	h, span := h.startSpan("AlertingRuleEnable", "alerting_rule", id, userSpace)
+	defer span.end(&err)
+
+	path := userSpacePath(userSpace, fmt.Sprintf("%s/%s/_enable", basePathAlertingRule, id))
+	return h.doRequest(http.MethodPost, path, nil, nil)
+}
+
+// AlertingRuleDisable permit to disable alerting rule on user space
+func (h *KibanaHandlerImpl) AlertingRuleDisable(id, userSpace string) (err error) {
+	h.log.Debug("Disable alerting rule", "scope", "alerting_rule", "name", id, "space", userSpace)
+	h, span := h.startSpan("AlertingRuleDisable", "alerting_rule", id, userSpace)
+	defer span.end(&err)
+
+	path := userSpacePath(userSpace, fmt.Sprintf("%s/%s/_disable", basePathAlertingRule, id))
+	return h.doRequest(http.MethodPost, path, nil, nil)
+}
+
+// AlertingRuleMuteAll permit to mute all alerts of alerting rule on user space
+func (h *KibanaHandlerImpl) AlertingRuleMuteAll(id, userSpace string) (err error) {
+	h.log.Debug("Mute all alerts of alerting rule", "scope", "alerting_rule", "name", id, "space", userSpace)
+	h, span := h.startSpan("AlertingRuleMuteAll", "alerting_rule", id, userSpace)
+	defer span.end(&err)
+
+	path := userSpacePath(userSpace, fmt.Sprintf("%s/%s/_mute_all", basePathAlertingRule, id))
+	return h.doRequest(http.MethodPost, path, nil, nil)
+}
@@ -0,0 +1,344 @@
+package kbhandler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var urlAlertingRule = fmt.Sprintf("%s/s/test/api/alerting/rule/test", baseURL)
+
+func (t *KibanaHandlerTestSuite) TestAlertingRuleGet() {
+
+	rawAlertingRule := `
+{
+	"id": "test",
+	"name": "cpu",
+	"consumer": "alerts",
+	"rule_type_id": ".index-threshold",
+	"tags": ["cpu"],
+	"schedule": {
+		"interval": "1m"
+	},
+	"params": {
+		"aggType": "avg",
+		"threshold": [90]
+	},
+	"actions": [
+		{
+			"group": "threshold met",
+			"id": "connector",
+			"uuid": "c6b7e7a9-7a3d-4d8a-8c9d-0b0a7f0a2c7e",
+			"connector_type_id": ".slack",
+			"params": {
+				"message": "CPU is high"
+			}
+		}
+	],
+	"throttle": null,
+	"notify_when": "onActionGroupChange",
+	"enabled": true,
+	"mute_all": false,
+	"muted_alert_ids": [],
+	"api_key_owner": "elastic",
+	"created_by": "elastic",
+	"updated_by": "elastic",
+	"created_at": "2023-01-01T00:00:00.000Z",
+	"updated_at": "2023-01-01T00:00:00.000Z",
+	"scheduled_task_id": "test",
+	"execution_status": {
+		"status": "ok",
+		"last_execution_date": "2023-01-01T00:00:00.000Z",
+		"last_duration": 100
+	}
+}
+	`
+
+	enabled := true
+	ruleTest := &AlertingRule{
+		ID:         "test",
+		Name:       "cpu",
+		Consumer:   "alerts",
+		RuleTypeID: ".index-threshold",
+		Tags:       []string{"cpu"},
+		Schedule: AlertingRuleSchedule{
+			Interval: "1m",
+		},
+		Params: map[string]interface{}{
+			"aggType":   "avg",
+			"threshold": []interface{}{float64(90)},
+		},
+		Actions: []AlertingRuleAction{
+			{
+				Group: "threshold met",
+				ID:    "connector",
+				Params: map[string]interface{}{
+					"message": "CPU is high",
+				},
+			},
+		},
+		NotifyWhen: "onActionGroupChange",
+		Enabled:    &enabled,
+	}
+
+	httpmock.RegisterResponder("GET", urlAlertingRule, func(req *http.Request) (*http.Response, error) {
+		resp := httpmock.NewStringResponse(200, rawAlertingRule)
+		return resp, nil
+	})
+
+	rule, err := t.kbHandler.AlertingRuleGet("test", "test")
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Empty(t.T(), cmp.Diff(ruleTest, rule))
+
+	// When not found
+	httpmock.RegisterResponder("GET", urlAlertingRule, httpmock.NewStringResponder(404, `{"statusCode": 404, "error": "Not Found"}`))
+	rule, err = t.kbHandler.AlertingRuleGet("test", "test")
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Nil(t.T(), rule)
+
+	// When error
+	httpmock.RegisterResponder("GET", urlAlertingRule, httpmock.NewErrorResponder(errors.New("fack error")))
+	_, err = t.kbHandler.AlertingRuleGet("test", "test")
+	assert.Error(t.T(), err)
+}
+
+func (t *KibanaHandlerTestSuite) TestAlertingRuleDelete() {
+
+	httpmock.RegisterResponder("DELETE", urlAlertingRule, httpmock.NewStringResponder(204, ""))
+
+	err := t.kbHandler.AlertingRuleDelete("test", "test")
+	if err != nil {
+		t.Fail(err.Error())
+	}
+
+	// When error
+	httpmock.RegisterResponder("DELETE", urlAlertingRule, httpmock.NewErrorResponder(errors.New("fack error")))
+	err = t.kbHandler.AlertingRuleDelete("test", "test")
+	assert.Error(t.T(), err)
+}
+
+func (t *KibanaHandlerTestSuite) TestAlertingRuleCreate() {
+
+	rawAlertingRule := `
+{
+	"id": "test",
+	"name": "cpu",
+	"consumer": "alerts",
+	"rule_type_id": ".index-threshold",
+	"schedule": {
+		"interval": "1m"
+	},
+	"params": {
+		"aggType": "avg",
+		"threshold": [90]
+	},
+	"enabled": true
+}
+	`
+
+	ruleTest := &AlertingRule{}
+	if err := json.Unmarshal([]byte(rawAlertingRule), ruleTest); err != nil {
+		panic(err)
+	}
+
+	httpmock.RegisterResponder("POST", urlAlertingRule, func(req *http.Request) (*http.Response, error) {
+		payload := map[string]interface{}{}
+		if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+			return nil, err
+		}
+		if _, ok := payload["id"]; ok {
+			return httpmock.NewStringResponse(400, `{}`), nil
+		}
+		return httpmock.NewStringResponse(200, rawAlertingRule), nil
+	})
+
+	err := t.kbHandler.AlertingRuleCreate(ruleTest, "test")
+	if err != nil {
+		t.Fail(err.Error())
+	}
+
+	// When error
+	httpmock.RegisterResponder("POST", urlAlertingRule, httpmock.NewErrorResponder(errors.New("fack error")))
+	err = t.kbHandler.AlertingRuleCreate(ruleTest, "test")
+	assert.Error(t.T(), err)
+}
+
+func (t *KibanaHandlerTestSuite) TestAlertingRuleUpdate() {
+
+	rawAlertingRule := `
+{
+	"id": "test",
+	"name": "cpu",
+	"consumer": "alerts",
+	"rule_type_id": ".index-threshold",
+	"schedule": {
+		"interval": "1m"
+	},
+	"params": {
+		"aggType": "avg",
+		"threshold": [90]
+	},
+	"enabled": true
+}
+	`
+
+	ruleTest := &AlertingRule{}
+	if err := json.Unmarshal([]byte(rawAlertingRule), ruleTest); err != nil {
+		panic(err)
+	}
+
+	httpmock.RegisterResponder("PUT", urlAlertingRule, func(req *http.Request) (*http.Response, error) {
+		payload := map[string]interface{}{}
+		if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+			return nil, err
+		}
+		for _, field := range []string{"id", "rule_type_id", "consumer", "enabled"} {
+			if _, ok := payload[field]; ok {
+				return httpmock.NewStringResponse(400, `{}`), nil
+			}
+		}
+		return httpmock.NewStringResponse(200, rawAlertingRule), nil
+	})
+	httpmock.RegisterResponder("POST", urlAlertingRule+"/_enable", httpmock.NewStringResponder(204, ""))
+
+	err := t.kbHandler.AlertingRuleUpdate(ruleTest, "test")
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Equal(t.T(), 1, httpmock.GetCallCountInfo()["POST "+urlAlertingRule+"/_enable"])
+
+	// When error
+	httpmock.RegisterResponder("PUT", urlAlertingRule, httpmock.NewErrorResponder(errors.New("fack error")))
+	err = t.kbHandler.AlertingRuleUpdate(ruleTest, "test")
+	assert.Error(t.T(), err)
+}
+
+func (t *KibanaHandlerTestSuite) TestAlertingRuleDiff() {
+	var actual, expected, original *AlertingRule
+
+	rawAlertingRule := `
+{
+	"id": "test",
+	"name": "cpu",
+	"consumer": "alerts",
+	"rule_type_id": ".index-threshold",
+	"schedule": {
+		"interval": "1m"
+	},
+	"params": {
+		"aggType": "avg",
+		"threshold": [90]
+	},
+	"notify_when": "onActionGroupChange",
+	"enabled": true
+}
+	`
+
+	expected = &AlertingRule{}
+	if err := json.Unmarshal([]byte(rawAlertingRule), expected); err != nil {
+		panic(err)
+	}
+
+	// When alerting rule not exist yet
+	actual = nil
+	diff, err := t.kbHandler.AlertingRuleDiff(actual, expected, nil)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.False(t.T(), diff.IsEmpty())
+	assert.Equal(t.T(), expected, diff.Patched)
+
+	// When alerting rule is the same, the execution status and audit fields are ignored
+	rawActual := `
+{
+	"id": "test",
+	"name": "cpu",
+	"consumer": "alerts",
+	"rule_type_id": ".index-threshold",
+	"schedule": {
+		"interval": "1m"
+	},
+	"params": {
+		"aggType": "avg",
+		"threshold": [90]
+	},
+	"notify_when": "onActionGroupChange",
+	"enabled": true,
+	"mute_all": false,
+	"api_key_owner": "elastic",
+	"updated_at": "2023-01-01T00:00:00.000Z",
+	"execution_status": {
+		"status": "ok"
+	}
+}
+	`
+	actual = &AlertingRule{}
+	if err := json.Unmarshal([]byte(rawActual), actual); err != nil {
+		panic(err)
+	}
+	diff, err = t.kbHandler.AlertingRuleDiff(actual, expected, expected)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.True(t.T(), diff.IsEmpty())
+
+	// When alerting rule is not the same
+	expected.Schedule.Interval = "5m"
+	diff, err = t.kbHandler.AlertingRuleDiff(actual, expected, actual)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.False(t.T(), diff.IsEmpty())
+	assert.Equal(t.T(), expected, diff.Patched)
+
+	// When kibana add default values
+	actual = &AlertingRule{}
+	expected = &AlertingRule{}
+	original = &AlertingRule{}
+	for _, rule := range []*AlertingRule{actual, expected, original} {
+		if err := json.Unmarshal([]byte(rawAlertingRule), rule); err != nil {
+			panic(err)
+		}
+	}
+	actual.Throttle = "1h"
+	diff, err = t.kbHandler.AlertingRuleDiff(actual, expected, original)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.True(t.T(), diff.IsEmpty())
+	assert.Equal(t.T(), actual, diff.Patched)
+}
+
+func (t *KibanaHandlerTestSuite) TestAlertingRuleEnableDisableMuteAll() {
+
+	for _, action := range []string{"_enable", "_disable", "_mute_all"} {
+		httpmock.RegisterResponder("POST", fmt.Sprintf("%s/%s", urlAlertingRule, action), httpmock.NewStringResponder(204, ""))
+	}
+
+	err := t.kbHandler.AlertingRuleEnable("test", "test")
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	err = t.kbHandler.AlertingRuleDisable("test", "test")
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	err = t.kbHandler.AlertingRuleMuteAll("test", "test")
+	if err != nil {
+		t.Fail(err.Error())
+	}
+
+	// When error
+	httpmock.RegisterResponder("POST", urlAlertingRule+"/_enable", httpmock.NewStringResponder(500, `{"statusCode": 500}`))
+	err = t.kbHandler.AlertingRuleEnable("test", "test")
+	assert.Error(t.T(), err)
+}
@@ -0,0 +1,190 @@
+package kbhandler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/disaster37/generic-objectmatcher/patch"
+	jsonIterator "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+)
+
+const (
+	basePathConnector = "/api/actions/connector" // Base URL to access on Kibana connectors
+	secretHashPrefix  = "sha256:"                // Prefix of hashed secret
+)
+
+// Connector is the Kibana connector (action) used by alerting rules
+// Secrets are write only, Kibana never return them
+type Connector struct {
+	ID              string                 `json:"id,omitempty"`
+	Name            string                 `json:"name"`
+	ConnectorTypeID string                 `json:"connector_type_id,omitempty"`
+	Config          map[string]interface{} `json:"config,omitempty"`
+	Secrets         map[string]interface{} `json:"secrets,omitempty"`
+}
+
+// connectorUpdateRequest is the body expected by Kibana to update connector
+type connectorUpdateRequest struct {
+	Name    string                 `json:"name"`
+	Config  map[string]interface{} `json:"config,omitempty"`
+	Secrets map[string]interface{} `json:"secrets,omitempty"`
+}
+
+// ConnectorCreate permit to create connector on user space
+func (h *KibanaHandlerImpl) ConnectorCreate(connector *Connector, userSpace string) (err error) {
+	h.log.Debug("Create connector", "scope", "connector", "name", connector.ID, "space", userSpace)
+	h, span := h.startSpan("ConnectorCreate", "connector", connector.ID, userSpace)
+	defer span.end(&err)
+
+	// Kibana not accept the ID on body
+	payload := *connector
+	payload.ID = ""
+
+	path := userSpacePath(userSpace, basePathConnector)
+	if connector.ID != "" {
+		path = fmt.Sprintf("%s/%s", path, connector.ID)
+	}
+
+	return h.doRequest(http.MethodPost, path, &payload, nil)
+}
+
+// ConnectorUpdate permit to update connector on user space
+func (h *KibanaHandlerImpl) ConnectorUpdate(connector *Connector, userSpace string) (err error) {
+	h.log.Debug("Update connector", "scope", "connector", "name", connector.ID, "space", userSpace)
+	h, span := h.startSpan("ConnectorUpdate", "connector", connector.ID, userSpace)
+	defer span.end(&err)
+
+	payload := &connectorUpdateRequest{
+		Name:    connector.Name,
+		Config:  connector.Config,
+		Secrets: connector.Secrets,
+	}
+
+	path := userSpacePath(userSpace, fmt.Sprintf("%s/%s", basePathConnector, connector.ID))
+	return h.doRequest(http.MethodPut, path, payload, nil)
+}
+
+// ConnectorDelete permit to delete connector on user space
+func (h *KibanaHandlerImpl) ConnectorDelete(id, userSpace string) (err error) {
+	h.log.Debug("Delete connector", "scope", "connector", "name", id, "space", userSpace)
+	h, span := h.startSpan("ConnectorDelete", "connector", id, userSpace)
+	defer span.end(&err)
+
+	path := userSpacePath(userSpace, fmt.Sprintf("%s/%s", basePathConnector, id))
+	return h.doRequest(http.MethodDelete, path, nil, nil)
+}
+
+// ConnectorGet permit to get connector on user space
+// It return nil if connector not exist
+func (h *KibanaHandlerImpl) ConnectorGet(id, userSpace string) (connector *Connector, err error) {
+	h.log.Debug("Get connector", "scope", "connector", "name", id, "space", userSpace)
+	h, span := h.startSpan("ConnectorGet", "connector", id, userSpace)
+	defer span.end(&err)
+
+	connector = &Connector{}
+	path := userSpacePath(userSpace, fmt.Sprintf("%s/%s", basePathConnector, id))
+	if err = h.doRequest(http.MethodGet, path, nil, connector); err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return connector, nil
+}
+
+// ConnectorDiff permit to diff connector
+// Kibana never return secrets, so they are compared with the original object instead of the actual object.
+// The original object can store secrets hashed with ConnectorHashSecrets to not keep them in clear.
+// Secrets are considered unchanged when there are no original object.
+func (h *KibanaHandlerImpl) ConnectorDiff(actualObject, expectedObject, originalObject *Connector) (patchResult *patch.PatchResult, err error) {
+	defer h.observeDiff("connector", actualObject == nil, &patchResult)
+
+	// If not yet exist
+	if actualObject == nil {
+		expected, err := jsonIterator.ConfigCompatibleWithStandardLibrary.Marshal(expectedObject)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to convert expected object to byte sequence")
+		}
+
+		return &patch.PatchResult{
+			Patch:    expected,
+			Current:  expected,
+			Modified: expected,
+			Original: nil,
+			Patched:  expectedObject,
+		}, nil
+	}
+
+	// Secrets are write only, so we diff without them
+	actual := *actualObject
+	actual.Secrets = nil
+	expected := *expectedObject
+	expected.Secrets = nil
+	var original *Connector
+	if originalObject != nil {
+		o := *originalObject
+		o.Secrets = nil
+		original = &o
+	}
+
+	patchResult, err = patch.DefaultPatchMaker.Calculate(&actual, &expected, original)
+	if err != nil {
+		return nil, err
+	}
+
+	if originalObject != nil && !reflect.DeepEqual(hashSecrets(expectedObject.Secrets), hashSecrets(originalObject.Secrets)) {
+		p := map[string]interface{}{}
+		if err = jsonIterator.ConfigCompatibleWithStandardLibrary.Unmarshal(patchResult.Patch, &p); err != nil {
+			return nil, errors.Wrap(err, "Failed to decode patch")
+		}
+		p["secrets"] = hashSecrets(expectedObject.Secrets)
+		if patchResult.Patch, err = jsonIterator.ConfigCompatibleWithStandardLibrary.Marshal(p); err != nil {
+			return nil, errors.Wrap(err, "Failed to convert patch to byte sequence")
+		}
+	}
+
+	// The object to apply need to have the secrets
+	patchResult.Patched.(*Connector).Secrets = expectedObject.Secrets
+
+	return patchResult, nil
+}
+
+// ConnectorHashSecrets return copy of connector where secrets are replaced by their sha256 hash
+// It can be used to store the original object without secrets in clear
+func ConnectorHashSecrets(connector *Connector) *Connector {
+	if connector == nil {
+		return nil
+	}
+
+	c := *connector
+	c.Secrets = hashSecrets(connector.Secrets)
+
+	return &c
+}
+
+// hashSecrets return secrets where each value is replaced by its sha256 hash
+// Values already hashed are kept as is
+func hashSecrets(secrets map[string]interface{}) map[string]interface{} {
+	if len(secrets) == 0 {
+		return nil
+	}
+
+	hashed := make(map[string]interface{}, len(secrets))
+	for key, value := range secrets {
+		if s, ok := value.(string); ok && strings.HasPrefix(s, secretHashPrefix) {
+			hashed[key] = s
+			continue
+		}
+		b, _ := jsonIterator.ConfigCompatibleWithStandardLibrary.Marshal(value)
+		sum := sha256.Sum256(b)
+		hashed[key] = secretHashPrefix + hex.EncodeToString(sum[:])
+	}
+
+	return hashed
+}
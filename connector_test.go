@@ -0,0 +1,265 @@
+package kbhandler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var urlConnector = fmt.Sprintf("%s/s/test/api/actions/connector/test", baseURL)
+
+func (t *KibanaHandlerTestSuite) TestConnectorGet() {
+
+	rawConnector := `
+{
+	"id": "test",
+	"name": "slack",
+	"connector_type_id": ".webhook",
+	"config": {
+		"url": "https://hooks.slack.com",
+		"method": "post"
+	},
+	"is_preconfigured": false,
+	"is_deprecated": false,
+	"is_missing_secrets": false,
+	"referenced_by_count": 2
+}
+	`
+
+	connectorTest := &Connector{}
+	if err := json.Unmarshal([]byte(rawConnector), connectorTest); err != nil {
+		panic(err)
+	}
+
+	httpmock.RegisterResponder("GET", urlConnector, func(req *http.Request) (*http.Response, error) {
+		resp := httpmock.NewStringResponse(200, rawConnector)
+		return resp, nil
+	})
+
+	connector, err := t.kbHandler.ConnectorGet("test", "test")
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Empty(t.T(), cmp.Diff(connectorTest, connector))
+
+	// When not found
+	httpmock.RegisterResponder("GET", urlConnector, httpmock.NewStringResponder(404, `{"statusCode": 404, "error": "Not Found"}`))
+	connector, err = t.kbHandler.ConnectorGet("test", "test")
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Nil(t.T(), connector)
+
+	// When error
+	httpmock.RegisterResponder("GET", urlConnector, httpmock.NewErrorResponder(errors.New("fack error")))
+	_, err = t.kbHandler.ConnectorGet("test", "test")
+	assert.Error(t.T(), err)
+}
+
+func (t *KibanaHandlerTestSuite) TestConnectorDelete() {
+
+	httpmock.RegisterResponder("DELETE", urlConnector, httpmock.NewStringResponder(204, ""))
+
+	err := t.kbHandler.ConnectorDelete("test", "test")
+	if err != nil {
+		t.Fail(err.Error())
+	}
+
+	// When error
+	httpmock.RegisterResponder("DELETE", urlConnector, httpmock.NewErrorResponder(errors.New("fack error")))
+	err = t.kbHandler.ConnectorDelete("test", "test")
+	assert.Error(t.T(), err)
+}
+
+func (t *KibanaHandlerTestSuite) TestConnectorCreate() {
+
+	rawConnector := `
+{
+	"id": "test",
+	"name": "slack",
+	"connector_type_id": ".webhook",
+	"config": {
+		"url": "https://hooks.slack.com",
+		"method": "post"
+	},
+	"secrets": {
+		"password": "changeme"
+	}
+}
+	`
+
+	connectorTest := &Connector{}
+	if err := json.Unmarshal([]byte(rawConnector), connectorTest); err != nil {
+		panic(err)
+	}
+
+	httpmock.RegisterResponder("POST", urlConnector, func(req *http.Request) (*http.Response, error) {
+		payload := map[string]interface{}{}
+		if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+			return nil, err
+		}
+		if _, ok := payload["id"]; ok {
+			return httpmock.NewStringResponse(400, `{}`), nil
+		}
+		if _, ok := payload["secrets"]; !ok {
+			return httpmock.NewStringResponse(400, `{}`), nil
+		}
+		return httpmock.NewStringResponse(200, `{"id": "test", "name": "slack", "connector_type_id": ".webhook"}`), nil
+	})
+
+	err := t.kbHandler.ConnectorCreate(connectorTest, "test")
+	if err != nil {
+		t.Fail(err.Error())
+	}
+
+	// When error
+	httpmock.RegisterResponder("POST", urlConnector, httpmock.NewErrorResponder(errors.New("fack error")))
+	err = t.kbHandler.ConnectorCreate(connectorTest, "test")
+	assert.Error(t.T(), err)
+}
+
+func (t *KibanaHandlerTestSuite) TestConnectorUpdate() {
+
+	rawConnector := `
+{
+	"id": "test",
+	"name": "slack",
+	"connector_type_id": ".webhook",
+	"config": {
+		"url": "https://hooks.slack.com",
+		"method": "post"
+	},
+	"secrets": {
+		"password": "changeme"
+	}
+}
+	`
+
+	connectorTest := &Connector{}
+	if err := json.Unmarshal([]byte(rawConnector), connectorTest); err != nil {
+		panic(err)
+	}
+
+	httpmock.RegisterResponder("PUT", urlConnector, func(req *http.Request) (*http.Response, error) {
+		payload := map[string]interface{}{}
+		if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+			return nil, err
+		}
+		for _, field := range []string{"id", "connector_type_id"} {
+			if _, ok := payload[field]; ok {
+				return httpmock.NewStringResponse(400, `{}`), nil
+			}
+		}
+		return httpmock.NewStringResponse(200, `{"id": "test", "name": "slack", "connector_type_id": ".webhook"}`), nil
+	})
+
+	err := t.kbHandler.ConnectorUpdate(connectorTest, "test")
+	if err != nil {
+		t.Fail(err.Error())
+	}
+
+	// When error
+	httpmock.RegisterResponder("PUT", urlConnector, httpmock.NewErrorResponder(errors.New("fack error")))
+	err = t.kbHandler.ConnectorUpdate(connectorTest, "test")
+	assert.Error(t.T(), err)
+}
+
+func (t *KibanaHandlerTestSuite) TestConnectorDiff() {
+	var actual, expected, original *Connector
+
+	rawConnector := `
+{
+	"id": "test",
+	"name": "slack",
+	"connector_type_id": ".webhook",
+	"config": {
+		"url": "https://hooks.slack.com",
+		"method": "post"
+	},
+	"secrets": {
+		"password": "changeme"
+	}
+}
+	`
+
+	expected = &Connector{}
+	if err := json.Unmarshal([]byte(rawConnector), expected); err != nil {
+		panic(err)
+	}
+
+	// When connector not exist yet
+	actual = nil
+	diff, err := t.kbHandler.ConnectorDiff(actual, expected, nil)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.False(t.T(), diff.IsEmpty())
+	assert.Equal(t.T(), expected, diff.Patched)
+
+	// When connector is the same, Kibana not return secrets
+	rawActual := `
+{
+	"id": "test",
+	"name": "slack",
+	"connector_type_id": ".webhook",
+	"config": {
+		"url": "https://hooks.slack.com",
+		"method": "post"
+	},
+	"is_missing_secrets": false
+}
+	`
+	actual = &Connector{}
+	if err := json.Unmarshal([]byte(rawActual), actual); err != nil {
+		panic(err)
+	}
+	original = &Connector{}
+	if err := json.Unmarshal([]byte(rawConnector), original); err != nil {
+		panic(err)
+	}
+	diff, err = t.kbHandler.ConnectorDiff(actual, expected, original)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.True(t.T(), diff.IsEmpty())
+	assert.Equal(t.T(), expected, diff.Patched)
+
+	// When connector is the same without original object
+	diff, err = t.kbHandler.ConnectorDiff(actual, expected, nil)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.True(t.T(), diff.IsEmpty())
+
+	// When connector is the same and original secrets are hashed
+	diff, err = t.kbHandler.ConnectorDiff(actual, expected, ConnectorHashSecrets(original))
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.True(t.T(), diff.IsEmpty())
+
+	// When secrets changed
+	expected.Secrets["password"] = "changeme2"
+	diff, err = t.kbHandler.ConnectorDiff(actual, expected, ConnectorHashSecrets(original))
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.False(t.T(), diff.IsEmpty())
+	assert.NotContains(t.T(), string(diff.Patch), "changeme2")
+	assert.Equal(t.T(), expected, diff.Patched)
+
+	// When config changed
+	expected.Secrets["password"] = "changeme"
+	expected.Config["method"] = "put"
+	diff, err = t.kbHandler.ConnectorDiff(actual, expected, original)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.False(t.T(), diff.IsEmpty())
+	assert.Equal(t.T(), expected, diff.Patched)
+}
@@ -0,0 +1,97 @@
+package kbhandler
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+
+	jsonIterator "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+)
+
+// DashboardImportResult is the response of Kibana when import saved objects
+type DashboardImportResult struct {
+	Success      bool                         `json:"success"`
+	SuccessCount int                          `json:"successCount"`
+	Errors       []DashboardImportResultError `json:"errors,omitempty"`
+}
+
+// DashboardImportResultError is the error returned by Kibana for an object it failed to import
+type DashboardImportResultError struct {
+	ID    string                 `json:"id"`
+	Type  string                 `json:"type"`
+	Title string                 `json:"title,omitempty"`
+	Error map[string]interface{} `json:"error,omitempty"`
+}
+
+// DashboardExport permit to export dashboard with all its referenced saved objects (visualizations, data views, searches)
+// It return a NDJSON bundle that can be imported on another Kibana with DashboardImport
+func (h *KibanaHandlerImpl) DashboardExport(id, userSpace string) (data []byte, err error) {
+	h.log.Debug("Export dashboard", "scope", "dashboard", "name", id, "space", userSpace)
+	h, span := h.startSpan("DashboardExport", "dashboard", id, userSpace)
+	defer span.end(&err)
+
+	objects := []map[string]string{
+		{
+			"type": "dashboard",
+			"id":   id,
+		},
+	}
+
+	data, err = h.client.KibanaSavedObject.Export(nil, objects, true, userSpace)
+	return data, wrapError(err)
+}
+
+// DashboardImport permit to import NDJSON bundle generated by DashboardExport on user space
+// overwrite replace the existing objects, createNewCopies generate new IDs for all imported objects. They can't be used together.
+func (h *KibanaHandlerImpl) DashboardImport(data []byte, userSpace string, overwrite, createNewCopies bool) (err error) {
+	h.log.Debug("Import dashboard", "scope", "dashboard", "space", userSpace)
+	h, span := h.startSpan("DashboardImport", "dashboard", "", userSpace)
+	defer span.end(&err)
+
+	if overwrite && createNewCopies {
+		return errors.New("You can't use overwrite and createNewCopies at the same time")
+	}
+
+	path := userSpacePath(userSpace, "/api/saved_objects/_import")
+	if h.isDryRun() {
+		switch {
+		case overwrite:
+			path += "?overwrite=true"
+		case createNewCopies:
+			path += "?createNewCopies=true"
+		}
+		return h.record(http.MethodPost, path, data)
+	}
+
+	req := h.newRequest().SetFileReader("file", "export.ndjson", bytes.NewReader(data))
+	if overwrite {
+		req.SetQueryParam("overwrite", "true")
+	}
+	if createNewCopies {
+		req.SetQueryParam("createNewCopies", "true")
+	}
+
+	resp, err := req.Post(path)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode() >= 300 {
+		return responseError(resp)
+	}
+
+	result := &DashboardImportResult{}
+	if err = jsonIterator.ConfigCompatibleWithStandardLibrary.Unmarshal(resp.Body(), result); err != nil {
+		return errors.Wrap(err, "Failed to decode import result")
+	}
+	if !result.Success {
+		errs := make([]string, 0, len(result.Errors))
+		for _, e := range result.Errors {
+			errs = append(errs, fmt.Sprintf("%s/%s (%v)", e.Type, e.ID, e.Error["type"]))
+		}
+		return errors.Errorf("Failed to import objects: %s", strings.Join(errs, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package kbhandler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+const rawDashboardExport = `{"id":"test","type":"dashboard","attributes":{"title":"test"},"references":[{"id":"vis","type":"visualization","name":"panel_0"}]}
+{"id":"vis","type":"visualization","attributes":{"title":"vis"},"references":[{"id":"logs-*","type":"index-pattern","name":"kibanaSavedObjectMeta.searchSourceJSON.index"}]}
+{"id":"logs-*","type":"index-pattern","attributes":{"title":"logs-*"},"references":[]}
+`
+
+func (t *KibanaHandlerTestSuite) TestDashboardExport() {
+
+	url := fmt.Sprintf("%s/s/test/api/saved_objects/_export", baseURL)
+
+	httpmock.RegisterResponder("POST", url, func(req *http.Request) (*http.Response, error) {
+		payload := map[string]interface{}{}
+		if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+			return nil, err
+		}
+		if payload["includeReferencesDeep"] != true {
+			return httpmock.NewStringResponse(400, `{}`), nil
+		}
+		return httpmock.NewStringResponse(200, rawDashboardExport), nil
+	})
+
+	data, err := t.kbHandler.DashboardExport("test", "test")
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Equal(t.T(), rawDashboardExport, string(data))
+
+	// When error
+	httpmock.RegisterResponder("POST", url, httpmock.NewErrorResponder(errors.New("fack error")))
+	_, err = t.kbHandler.DashboardExport("test", "test")
+	assert.Error(t.T(), err)
+}
+
+func (t *KibanaHandlerTestSuite) TestDashboardImport() {
+
+	url := fmt.Sprintf("%s/s/test/api/saved_objects/_import", baseURL)
+
+	httpmock.RegisterResponder("POST", url, func(req *http.Request) (*http.Response, error) {
+		if req.URL.Query().Get("createNewCopies") != "true" {
+			return httpmock.NewStringResponse(400, `{}`), nil
+		}
+		file, _, err := req.FormFile("file")
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return nil, err
+		}
+		if string(data) != rawDashboardExport {
+			return httpmock.NewStringResponse(400, `{}`), nil
+		}
+		return httpmock.NewStringResponse(200, `{"success": true, "successCount": 3}`), nil
+	})
+
+	err := t.kbHandler.DashboardImport([]byte(rawDashboardExport), "test", false, true)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+
+	// When overwrite and createNewCopies
+	err = t.kbHandler.DashboardImport([]byte(rawDashboardExport), "test", true, true)
+	assert.Error(t.T(), err)
+
+	// When some objects failed to import
+	httpmock.RegisterResponder("POST", url, httpmock.NewStringResponder(200, `
+{
+	"success": false,
+	"successCount": 2,
+	"errors": [
+		{
+			"id": "test",
+			"type": "dashboard",
+			"title": "test",
+			"error": {
+				"type": "conflict"
+			}
+		}
+	]
+}
+	`))
+	err = t.kbHandler.DashboardImport([]byte(rawDashboardExport), "test", false, false)
+	assert.ErrorContains(t.T(), err, "dashboard/test (conflict)")
+
+	// When error
+	httpmock.RegisterResponder("POST", url, httpmock.NewErrorResponder(errors.New("fack error")))
+	err = t.kbHandler.DashboardImport([]byte(rawDashboardExport), "test", true, false)
+	assert.Error(t.T(), err)
+}
@@ -0,0 +1,145 @@
+package kbhandler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/disaster37/generic-objectmatcher/patch"
+	jsonIterator "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+)
+
+const (
+	basePathDataView = "/api/data_views" // Base URL to access on Kibana data views
+)
+
+// DataView is the Kibana data view (index pattern)
+type DataView struct {
+	ID              string                          `json:"id,omitempty"`
+	Title           string                          `json:"title"`
+	Name            string                          `json:"name,omitempty"`
+	TimeFieldName   string                          `json:"timeFieldName,omitempty"`
+	SourceFilters   []DataViewSourceFilter          `json:"sourceFilters,omitempty"`
+	FieldFormats    map[string]DataViewFieldFormat  `json:"fieldFormats,omitempty"`
+	RuntimeFieldMap map[string]DataViewRuntimeField `json:"runtimeFieldMap,omitempty"`
+	AllowNoIndex    bool                            `json:"allowNoIndex"`
+}
+
+// DataViewSourceFilter is the field filtered from the source
+type DataViewSourceFilter struct {
+	Value string `json:"value"`
+}
+
+// DataViewFieldFormat is the format applied on field
+type DataViewFieldFormat struct {
+	ID     string                 `json:"id"`
+	Params map[string]interface{} `json:"params,omitempty"`
+}
+
+// DataViewRuntimeField is the runtime field computed on the data view
+type DataViewRuntimeField struct {
+	Type   string                      `json:"type"`
+	Script *DataViewRuntimeFieldScript `json:"script,omitempty"`
+}
+
+// DataViewRuntimeFieldScript is the painless script of the runtime field
+type DataViewRuntimeFieldScript struct {
+	Source string `json:"source"`
+}
+
+// dataViewRequest is the request / response wrapper used by data view API
+type dataViewRequest struct {
+	DataView *DataView `json:"data_view"`
+	Override bool      `json:"override,omitempty"`
+}
+
+// DataViewCreate permit to create data view on user space
+func (h *KibanaHandlerImpl) DataViewCreate(dataView *DataView, userSpace string) (err error) {
+	h.log.Debug("Create data view", "scope", "data_view", "name", dataView.ID, "space", userSpace)
+	h, span := h.startSpan("DataViewCreate", "data_view", dataView.ID, userSpace)
+	defer span.end(&err)
+
+	path := userSpacePath(userSpace, fmt.Sprintf("%s/data_view", basePathDataView))
+	return h.doRequest(http.MethodPost, path, &dataViewRequest{DataView: dataView}, nil)
+}
+
+// DataViewUpdate permit to update data view on user space
+func (h *KibanaHandlerImpl) DataViewUpdate(dataView *DataView, userSpace string) (err error) {
+	h.log.Debug("Update data view", "scope", "data_view", "name", dataView.ID, "space", userSpace)
+	h, span := h.startSpan("DataViewUpdate", "data_view", dataView.ID, userSpace)
+	defer span.end(&err)
+
+	// Kibana not accept the ID on body
+	payload := *dataView
+	payload.ID = ""
+
+	path := userSpacePath(userSpace, fmt.Sprintf("%s/data_view/%s", basePathDataView, dataView.ID))
+	return h.doRequest(http.MethodPost, path, &dataViewRequest{DataView: &payload}, nil)
+}
+
+// DataViewDelete permit to delete data view on user space
+func (h *KibanaHandlerImpl) DataViewDelete(id, userSpace string) (err error) {
+	h.log.Debug("Delete data view", "scope", "data_view", "name", id, "space", userSpace)
+	h, span := h.startSpan("DataViewDelete", "data_view", id, userSpace)
+	defer span.end(&err)
+
+	path := userSpacePath(userSpace, fmt.Sprintf("%s/data_view/%s", basePathDataView, id))
+	return h.doRequest(http.MethodDelete, path, nil, nil)
+}
+
+// DataViewGet permit to get data view on user space
+// It return nil if data view not exist
+func (h *KibanaHandlerImpl) DataViewGet(id, userSpace string) (dataView *DataView, err error) {
+	h.log.Debug("Get data view", "scope", "data_view", "name", id, "space", userSpace)
+	h, span := h.startSpan("DataViewGet", "data_view", id, userSpace)
+	defer span.end(&err)
+
+	result := &dataViewRequest{}
+	path := userSpacePath(userSpace, fmt.Sprintf("%s/data_view/%s", basePathDataView, id))
+	if err = h.doRequest(http.MethodGet, path, nil, result); err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return result.DataView, nil
+}
+
+// DataViewDiff permit to diff data view
+func (h *KibanaHandlerImpl) DataViewDiff(actualObject, expectedObject, originalObject *DataView) (patchResult *patch.PatchResult, err error) {
+	defer h.observeDiff("data_view", actualObject == nil, &patchResult)
+
+	// If not yet exist
+	if actualObject == nil {
+		expected, err := jsonIterator.ConfigCompatibleWithStandardLibrary.Marshal(expectedObject)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to convert expected object to byte sequence")
+		}
+
+		return &patch.PatchResult{
+			Patch:    expected,
+			Current:  expected,
+			Modified: expected,
+			Original: nil,
+			Patched:  expectedObject,
+		}, nil
+	}
+
+	return patch.DefaultPatchMaker.Calculate(actualObject, expectedObject, originalObject)
+}
+
+// SetDefaultDataView permit to set the default data view of user space
+func (h *KibanaHandlerImpl) SetDefaultDataView(id, userSpace string) (err error) {
+	h.log.Debug("Set default data view", "scope", "data_view", "name", id, "space", userSpace)
+	h, span := h.startSpan("SetDefaultDataView", "data_view", id, userSpace)
+	defer span.end(&err)
+
+	payload := map[string]interface{}{
+		"data_view_id": id,
+		"force":        true,
+	}
+
+	path := userSpacePath(userSpace, fmt.Sprintf("%s/default", basePathDataView))
+	return h.doRequest(http.MethodPost, path, payload, nil)
+}
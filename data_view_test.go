@@ -0,0 +1,319 @@
+package kbhandler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var urlDataView = fmt.Sprintf("%s/s/test/api/data_views/data_view/test", baseURL)
+
+func (t *KibanaHandlerTestSuite) TestDataViewGet() {
+
+	rawDataView := `
+{
+	"data_view": {
+		"id": "test",
+		"version": "WzEsMV0=",
+		"title": "logs-*",
+		"name": "Logs",
+		"timeFieldName": "@timestamp",
+		"sourceFilters": [
+			{
+				"value": "password"
+			}
+		],
+		"fieldFormats": {
+			"bytes": {
+				"id": "bytes"
+			}
+		},
+		"runtimeFieldMap": {
+			"day": {
+				"type": "keyword",
+				"script": {
+					"source": "emit('monday')"
+				}
+			}
+		},
+		"fields": {},
+		"typeMeta": {},
+		"namespaces": ["test"],
+		"allowNoIndex": false
+	}
+}
+	`
+
+	dataViewTest := &DataView{
+		ID:            "test",
+		Title:         "logs-*",
+		Name:          "Logs",
+		TimeFieldName: "@timestamp",
+		SourceFilters: []DataViewSourceFilter{
+			{
+				Value: "password",
+			},
+		},
+		FieldFormats: map[string]DataViewFieldFormat{
+			"bytes": {
+				ID: "bytes",
+			},
+		},
+		RuntimeFieldMap: map[string]DataViewRuntimeField{
+			"day": {
+				Type: "keyword",
+				Script: &DataViewRuntimeFieldScript{
+					Source: "emit('monday')",
+				},
+			},
+		},
+	}
+
+	httpmock.RegisterResponder("GET", urlDataView, func(req *http.Request) (*http.Response, error) {
+		resp := httpmock.NewStringResponse(200, rawDataView)
+		return resp, nil
+	})
+
+	dataView, err := t.kbHandler.DataViewGet("test", "test")
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Empty(t.T(), cmp.Diff(dataViewTest, dataView))
+
+	// When not found
+	httpmock.RegisterResponder("GET", urlDataView, httpmock.NewStringResponder(404, `{"statusCode": 404, "error": "Not Found"}`))
+	dataView, err = t.kbHandler.DataViewGet("test", "test")
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Nil(t.T(), dataView)
+
+	// When error
+	httpmock.RegisterResponder("GET", urlDataView, httpmock.NewErrorResponder(errors.New("fack error")))
+	_, err = t.kbHandler.DataViewGet("test", "test")
+	assert.Error(t.T(), err)
+}
+
+func (t *KibanaHandlerTestSuite) TestDataViewDelete() {
+
+	httpmock.RegisterResponder("DELETE", urlDataView, func(req *http.Request) (*http.Response, error) {
+		resp := httpmock.NewStringResponse(200, "")
+		return resp, nil
+	})
+
+	err := t.kbHandler.DataViewDelete("test", "test")
+	if err != nil {
+		t.Fail(err.Error())
+	}
+
+	// When error
+	httpmock.RegisterResponder("DELETE", urlDataView, httpmock.NewErrorResponder(errors.New("fack error")))
+	err = t.kbHandler.DataViewDelete("test", "test")
+	assert.Error(t.T(), err)
+}
+
+func (t *KibanaHandlerTestSuite) TestDataViewCreate() {
+
+	url := fmt.Sprintf("%s/s/test/api/data_views/data_view", baseURL)
+
+	dataView := &DataView{
+		ID:            "test",
+		Title:         "logs-*",
+		TimeFieldName: "@timestamp",
+	}
+
+	httpmock.RegisterResponder("POST", url, func(req *http.Request) (*http.Response, error) {
+		payload := &dataViewRequest{}
+		if err := json.NewDecoder(req.Body).Decode(payload); err != nil {
+			return nil, err
+		}
+		if payload.DataView == nil || payload.DataView.ID != "test" {
+			return httpmock.NewStringResponse(400, `{}`), nil
+		}
+		return httpmock.NewStringResponse(200, `{"data_view": {"id": "test", "title": "logs-*"}}`), nil
+	})
+
+	err := t.kbHandler.DataViewCreate(dataView, "test")
+	if err != nil {
+		t.Fail(err.Error())
+	}
+
+	// When error
+	httpmock.RegisterResponder("POST", url, httpmock.NewStringResponder(400, `{"statusCode": 400, "error": "Bad Request"}`))
+	err = t.kbHandler.DataViewCreate(dataView, "test")
+	assert.Error(t.T(), err)
+}
+
+func (t *KibanaHandlerTestSuite) TestDataViewUpdate() {
+
+	dataView := &DataView{
+		ID:            "test",
+		Title:         "logs-*",
+		TimeFieldName: "@timestamp",
+	}
+
+	httpmock.RegisterResponder("POST", urlDataView, func(req *http.Request) (*http.Response, error) {
+		payload := map[string]map[string]interface{}{}
+		if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+			return nil, err
+		}
+		if _, ok := payload["data_view"]["id"]; ok {
+			return httpmock.NewStringResponse(400, `{}`), nil
+		}
+		return httpmock.NewStringResponse(200, `{"data_view": {"id": "test", "title": "logs-*"}}`), nil
+	})
+
+	err := t.kbHandler.DataViewUpdate(dataView, "test")
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Equal(t.T(), "test", dataView.ID)
+
+	// When allow no index is switched to false
+	var payload map[string]map[string]interface{}
+	httpmock.RegisterResponder("POST", urlDataView, func(req *http.Request) (*http.Response, error) {
+		if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+			return nil, err
+		}
+		return httpmock.NewStringResponse(200, `{"data_view": {"id": "test", "title": "logs-*", "allowNoIndex": false}}`), nil
+	})
+	dataView.AllowNoIndex = false
+	err = t.kbHandler.DataViewUpdate(dataView, "test")
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Equal(t.T(), false, payload["data_view"]["allowNoIndex"])
+
+	// When error
+	httpmock.RegisterResponder("POST", urlDataView, httpmock.NewErrorResponder(errors.New("fack error")))
+	err = t.kbHandler.DataViewUpdate(dataView, "test")
+	assert.Error(t.T(), err)
+}
+
+func (t *KibanaHandlerTestSuite) TestDataViewDiff() {
+	var actual, expected, original *DataView
+
+	expected = &DataView{
+		ID:            "test",
+		Title:         "logs-*",
+		TimeFieldName: "@timestamp",
+		RuntimeFieldMap: map[string]DataViewRuntimeField{
+			"day": {
+				Type: "keyword",
+				Script: &DataViewRuntimeFieldScript{
+					Source: "emit('monday')",
+				},
+			},
+		},
+	}
+
+	// When data view not exist yet
+	actual = nil
+	diff, err := t.kbHandler.DataViewDiff(actual, expected, nil)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.False(t.T(), diff.IsEmpty())
+	assert.Equal(t.T(), expected, diff.Patched)
+
+	// When data view is the same
+	actual = &DataView{
+		ID:            "test",
+		Title:         "logs-*",
+		TimeFieldName: "@timestamp",
+		RuntimeFieldMap: map[string]DataViewRuntimeField{
+			"day": {
+				Type: "keyword",
+				Script: &DataViewRuntimeFieldScript{
+					Source: "emit('monday')",
+				},
+			},
+		},
+	}
+	diff, err = t.kbHandler.DataViewDiff(actual, expected, actual)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.True(t.T(), diff.IsEmpty())
+	assert.Equal(t.T(), expected, diff.Patched)
+
+	// When data view is not the same
+	expected = &DataView{
+		ID:            "test",
+		Title:         "logs-*",
+		TimeFieldName: "@timestamp",
+		RuntimeFieldMap: map[string]DataViewRuntimeField{
+			"day": {
+				Type: "keyword",
+				Script: &DataViewRuntimeFieldScript{
+					Source: "emit('tuesday')",
+				},
+			},
+		},
+	}
+	diff, err = t.kbHandler.DataViewDiff(actual, expected, actual)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.False(t.T(), diff.IsEmpty())
+	assert.Equal(t.T(), expected, diff.Patched)
+
+	// When kibana add default values
+	actual = &DataView{
+		ID:            "test",
+		Title:         "logs-*",
+		TimeFieldName: "@timestamp",
+		FieldFormats: map[string]DataViewFieldFormat{
+			"bytes": {
+				ID: "bytes",
+			},
+		},
+	}
+	expected = &DataView{
+		ID:            "test",
+		Title:         "logs-*",
+		TimeFieldName: "@timestamp",
+	}
+	original = &DataView{
+		ID:            "test",
+		Title:         "logs-*",
+		TimeFieldName: "@timestamp",
+	}
+	diff, err = t.kbHandler.DataViewDiff(actual, expected, original)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.True(t.T(), diff.IsEmpty())
+	assert.Equal(t.T(), actual, diff.Patched)
+}
+
+func (t *KibanaHandlerTestSuite) TestSetDefaultDataView() {
+
+	url := fmt.Sprintf("%s/s/test/api/data_views/default", baseURL)
+
+	httpmock.RegisterResponder("POST", url, func(req *http.Request) (*http.Response, error) {
+		payload := map[string]interface{}{}
+		if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+			return nil, err
+		}
+		if payload["data_view_id"] != "test" {
+			return httpmock.NewStringResponse(400, `{}`), nil
+		}
+		return httpmock.NewStringResponse(200, `{"acknowledged": true}`), nil
+	})
+
+	err := t.kbHandler.SetDefaultDataView("test", "test")
+	if err != nil {
+		t.Fail(err.Error())
+	}
+
+	// When error
+	httpmock.RegisterResponder("POST", url, httpmock.NewErrorResponder(errors.New("fack error")))
+	err = t.kbHandler.SetDefaultDataView("test", "test")
+	assert.Error(t.T(), err)
+}
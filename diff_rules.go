@@ -0,0 +1,270 @@
+package kbhandler
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/disaster37/generic-objectmatcher/patch"
+	jsonIterator "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+)
+
+// DiffKind is the object type where diff rules apply
+type DiffKind string
+
+const (
+	// DiffKindRole is used by RoleDiff
+	DiffKindRole DiffKind = "role"
+
+	// DiffKindUserSpace is used by UserSpaceDiff
+	DiffKindUserSpace DiffKind = "userSpace"
+
+	// DiffKindLogstashPipeline is used by LogstashPipelineDiff
+	DiffKindLogstashPipeline DiffKind = "logstashPipeline"
+)
+
+// DiffRules permit to normalize objects before diff them, to not report changes done by Kibana itself
+// The paths are JSON pointers (RFC 6901). The segment '*' match any key, and array items are always matched by '*'
+// like /kibana/*/spaces
+type DiffRules struct {
+	// IgnorePaths are the fields removed before diff
+	IgnorePaths []string
+
+	// EmptyAsNil remove null values, empty arrays and empty objects before diff
+	EmptyAsNil bool
+
+	// UnorderedArrays are the arrays where the order of items not matter
+	UnorderedArrays []string
+}
+
+// DefaultDiffRules are the rules used when no rules is provided for kind with WithDiffRules
+var DefaultDiffRules = map[DiffKind]DiffRules{
+	DiffKindRole: {
+		IgnorePaths: []string{
+			"/transient_metadata",
+			"/metadata/_reserved",
+		},
+		EmptyAsNil: true,
+		UnorderedArrays: []string{
+			"/elasticsearch/cluster",
+			"/elasticsearch/run_as",
+			"/elasticsearch/indices",
+			"/elasticsearch/indices/*/names",
+			"/elasticsearch/indices/*/privileges",
+			"/kibana",
+			"/kibana/*/base",
+			"/kibana/*/spaces",
+			"/kibana/*/feature/*",
+		},
+	},
+	DiffKindUserSpace: {
+		IgnorePaths: []string{
+			"/_reserved",
+		},
+		EmptyAsNil: true,
+		UnorderedArrays: []string{
+			"/disabledFeatures",
+		},
+	},
+	DiffKindLogstashPipeline: {
+		IgnorePaths: []string{
+			"/username",
+		},
+		EmptyAsNil: true,
+	},
+}
+
+// WithDiffRules permit to replace the default diff rules of kind
+func WithDiffRules(kind DiffKind, rules DiffRules) Option {
+	return func(h *KibanaHandlerImpl) {
+		if h.diffRules == nil {
+			h.diffRules = map[DiffKind]DiffRules{}
+		}
+		h.diffRules[kind] = rules
+	}
+}
+
+// diffRulesFor return the diff rules of kind
+func (h *KibanaHandlerImpl) diffRulesFor(kind DiffKind) DiffRules {
+	if rules, ok := h.diffRules[kind]; ok {
+		return rules
+	}
+
+	return DefaultDiffRules[kind]
+}
+
+// calculate permit to compute the diff after normalize the current, the expected and the original object with the rules of kind
+// The objects must be pointers. The extra options are applied after the rules, on the original object too
+func (h *KibanaHandlerImpl) calculate(kind DiffKind, actualObject, expectedObject, originalObject any, opts ...patch.CalculateOption) (patchResult *patch.PatchResult, err error) {
+	rules := h.diffRulesFor(kind)
+
+	var original any = originalObject
+	if !reflect.ValueOf(originalObject).IsNil() {
+		b, err := jsonIterator.ConfigCompatibleWithStandardLibrary.Marshal(originalObject)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to convert original object to byte sequence")
+		}
+		b, err = rules.normalize(b)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to normalize original object")
+		}
+		for _, opt := range opts {
+			if b, _, err = opt(b, b); err != nil {
+				return nil, errors.Wrap(err, "Failed to normalize original object")
+			}
+		}
+		original = json.RawMessage(b)
+	}
+
+	return patch.DefaultPatchMaker.Calculate(actualObject, expectedObject, original, append([]patch.CalculateOption{rules.calculateOption()}, opts...)...)
+}
+
+// calculateOption return the option that normalize current and expected object
+func (r DiffRules) calculateOption() patch.CalculateOption {
+	return func(current, modified []byte) ([]byte, []byte, error) {
+		current, err := r.normalize(current)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "Failed to normalize current object")
+		}
+		modified, err = r.normalize(modified)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "Failed to normalize expected object")
+		}
+
+		return current, modified, nil
+	}
+}
+
+// normalize apply the rules on JSON object
+func (r DiffRules) normalize(data []byte) ([]byte, error) {
+	var object any
+	if err := jsonIterator.ConfigCompatibleWithStandardLibrary.Unmarshal(data, &object); err != nil {
+		return nil, err
+	}
+
+	ignorePaths := parsePointers(r.IgnorePaths)
+	unorderedArrays := parsePointers(r.UnorderedArrays)
+
+	object, _ = r.normalizeValue(nil, object, ignorePaths, unorderedArrays)
+	if object == nil && r.EmptyAsNil {
+		object = map[string]any{}
+	}
+
+	return jsonIterator.ConfigCompatibleWithStandardLibrary.Marshal(object)
+}
+
+// normalizeValue normalize recursively the value at path
+// It return false if the value must be removed
+func (r DiffRules) normalizeValue(path []string, value any, ignorePaths, unorderedArrays [][]string) (any, bool) {
+	if len(path) > 0 && matchPointers(path, ignorePaths) {
+		return nil, false
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		for key, child := range v {
+			child, keep := r.normalizeValue(childPath(path, key), child, ignorePaths, unorderedArrays)
+			if !keep {
+				delete(v, key)
+				continue
+			}
+			v[key] = child
+		}
+		if r.EmptyAsNil && len(v) == 0 {
+			return nil, false
+		}
+		return v, true
+	case []any:
+		items := make([]any, 0, len(v))
+		for _, child := range v {
+			// Array items are always matched by '*', because they can be reordered
+			child, keep := r.normalizeValue(childPath(path, "*"), child, ignorePaths, unorderedArrays)
+			if !keep {
+				if r.EmptyAsNil {
+					continue
+				}
+				child = nil
+			}
+			items = append(items, child)
+		}
+		if r.EmptyAsNil && len(items) == 0 {
+			return nil, false
+		}
+		if matchPointers(path, unorderedArrays) {
+			sortItems(items)
+		}
+		return items, true
+	case nil:
+		return nil, !r.EmptyAsNil
+	default:
+		return v, true
+	}
+}
+
+// childPath return a new path with the segment
+func childPath(path []string, segment string) []string {
+	child := make([]string, len(path), len(path)+1)
+	copy(child, path)
+
+	return append(child, segment)
+}
+
+// sortItems sort array items by their JSON representation
+func sortItems(items []any) {
+	keys := make(map[int]string, len(items))
+	indexes := make([]int, len(items))
+	for i, item := range items {
+		b, _ := jsonIterator.ConfigCompatibleWithStandardLibrary.Marshal(item)
+		keys[i] = string(b)
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return keys[indexes[i]] < keys[indexes[j]]
+	})
+
+	sorted := make([]any, len(items))
+	for i, index := range indexes {
+		sorted[i] = items[index]
+	}
+	copy(items, sorted)
+}
+
+// parsePointers split JSON pointers on segments
+func parsePointers(pointers []string) [][]string {
+	segments := make([][]string, 0, len(pointers))
+	for _, pointer := range pointers {
+		if pointer == "" || pointer == "/" {
+			continue
+		}
+		parts := strings.Split(strings.TrimPrefix(pointer, "/"), "/")
+		for i, part := range parts {
+			parts[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(part)
+		}
+		segments = append(segments, parts)
+	}
+
+	return segments
+}
+
+// matchPointers return true if path match one of pointers
+func matchPointers(path []string, pointers [][]string) bool {
+	for _, pointer := range pointers {
+		if len(pointer) != len(path) {
+			continue
+		}
+		match := true
+		for i := range pointer {
+			if pointer[i] != "*" && pointer[i] != path[i] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+
+	return false
+}
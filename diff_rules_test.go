@@ -0,0 +1,118 @@
+package kbhandler
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/disaster37/go-kibana-rest/v8/kbapi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffRulesNormalize(t *testing.T) {
+	rules := DiffRules{
+		IgnorePaths:     []string{"/ignored", "/items/*/ignored", "/a~1b"},
+		EmptyAsNil:      true,
+		UnorderedArrays: []string{"/items", "/items/*/values"},
+	}
+
+	data, err := rules.normalize([]byte(`{"ignored": 1, "a/b": 1, "empty": [], "null": null, "object": {}, "items": [{"name": "b", "values": [2, 1], "ignored": true}, {"name": "a"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.JSONEq(t, `{"items": [{"name": "a"}, {"name": "b", "values": [1, 2]}]}`, string(data))
+
+	// When empty are kept
+	rules.EmptyAsNil = false
+	data, err = rules.normalize([]byte(`{"empty": [], "null": null, "items": [{"name": "b"}, {"name": "a"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.JSONEq(t, `{"empty": [], "null": null, "items": [{"name": "a"}, {"name": "b"}]}`, string(data))
+}
+
+func (t *KibanaHandlerTestSuite) TestRoleDiffRules() {
+
+	rawActual := `
+{
+	"name": "test",
+	"metadata": {
+		"_reserved": false
+	},
+	"transient_metadata": {
+		"enabled": true
+	},
+	"elasticsearch": {
+		"cluster": ["monitor", "manage_ilm"],
+		"indices": [],
+		"run_as": []
+	},
+	"kibana": [
+		{
+			"base": [],
+			"feature": {
+				"discover": ["read", "all"]
+			},
+			"spaces": ["team-b", "team-a"]
+		},
+		{
+			"base": ["read"],
+			"spaces": ["default"]
+		}
+	]
+}
+	`
+	rawExpected := `
+{
+	"name": "test",
+	"elasticsearch": {
+		"cluster": ["manage_ilm", "monitor"]
+	},
+	"kibana": [
+		{
+			"base": ["read"],
+			"spaces": ["default"]
+		},
+		{
+			"feature": {
+				"discover": ["all", "read"]
+			},
+			"spaces": ["team-a", "team-b"]
+		}
+	]
+}
+	`
+	actual := &kbapi.KibanaRole{}
+	expected := &kbapi.KibanaRole{}
+	if err := json.Unmarshal([]byte(rawActual), actual); err != nil {
+		panic(err)
+	}
+	if err := json.Unmarshal([]byte(rawExpected), expected); err != nil {
+		panic(err)
+	}
+
+	// When only Kibana changes
+	patchResult, err := t.kbHandler.RoleDiff(actual, expected, expected)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.True(t.T(), patchResult.IsEmpty())
+
+	// When real change
+	expected.Elasticsearch.Cluster = []string{"monitor"}
+	patchResult, err = t.kbHandler.RoleDiff(actual, expected, nil)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.False(t.T(), patchResult.IsEmpty())
+	assert.Equal(t.T(), []string{"monitor"}, patchResult.Patched.(*kbapi.KibanaRole).Elasticsearch.Cluster)
+
+	// When custom rules
+	handler := *t.kbHandler.(*KibanaHandlerImpl)
+	WithDiffRules(DiffKindRole, DiffRules{})(&handler)
+	expected.Elasticsearch.Cluster = []string{"manage_ilm", "monitor"}
+	patchResult, err = handler.RoleDiff(actual, expected, nil)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.False(t.T(), patchResult.IsEmpty())
+}
@@ -0,0 +1,126 @@
+package kbhandler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/disaster37/go-kibana-rest/v8/kbapi"
+	"github.com/go-resty/resty/v2"
+	jsonIterator "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrNotFound is returned when the object not exist on Kibana
+	ErrNotFound = errors.New("not found")
+
+	// ErrConflict is returned when the object already exist or was modified in the meantime
+	ErrConflict = errors.New("conflict")
+
+	// ErrUnauthorized is returned when the credentials are wrong
+	ErrUnauthorized = errors.New("unauthorized")
+
+	// ErrForbidden is returned when the user not have the privileges to do the call
+	ErrForbidden = errors.New("forbidden")
+
+	// ErrVersionUnsupported is returned when the API is not available on the Kibana version
+	ErrVersionUnsupported = errors.New("version unsupported")
+
+	// ErrMissingReference is returned when the object reference other objects that not exist, like role on missing user spaces
+	ErrMissingReference = errors.New("missing reference")
+)
+
+// APIError is the error returned when Kibana answer with error status code
+// It can be checked with errors.Is against ErrNotFound, ErrConflict, ErrUnauthorized, ErrForbidden and ErrVersionUnsupported
+type APIError struct {
+	StatusCode int
+
+	// Status is the HTTP status text, like "404 Not Found"
+	Status string
+
+	// Body is the response body. It's empty when the call is done by go-kibana-rest, because it not keep the response body
+	Body string
+
+	err error
+}
+
+// Error return the error message
+func (e *APIError) Error() string {
+	message := e.Body
+	if message == "" {
+		message = e.Status
+	}
+	if e.err != nil {
+		return fmt.Sprintf("Kibana answer with status code %d (%s): %s", e.StatusCode, e.err.Error(), message)
+	}
+	return fmt.Sprintf("Kibana answer with status code %d: %s", e.StatusCode, message)
+}
+
+// Unwrap return the sentinel error matching the status code
+func (e *APIError) Unwrap() error {
+	return e.err
+}
+
+// NewAPIError create APIError from status code and response body
+func NewAPIError(statusCode int, body string) *APIError {
+	apiErr := &APIError{
+		StatusCode: statusCode,
+		Body:       body,
+	}
+
+	switch statusCode {
+	case http.StatusUnauthorized:
+		apiErr.err = ErrUnauthorized
+	case http.StatusForbidden:
+		apiErr.err = ErrForbidden
+	case http.StatusConflict:
+		apiErr.err = ErrConflict
+	case http.StatusNotFound:
+		if isRouteNotFound(body) {
+			apiErr.err = ErrVersionUnsupported
+		} else {
+			apiErr.err = ErrNotFound
+		}
+	case http.StatusGone, http.StatusNotImplemented:
+		apiErr.err = ErrVersionUnsupported
+	}
+
+	return apiErr
+}
+
+// responseError create APIError from the error response of Kibana
+func responseError(resp *resty.Response) *APIError {
+	apiErr := NewAPIError(resp.StatusCode(), string(resp.Body()))
+	apiErr.Status = resp.Status()
+
+	return apiErr
+}
+
+// wrapError convert the error returned by go-kibana-rest to APIError
+// go-kibana-rest only keep the status text, so the body of APIError is empty.
+// Other errors (network, decode, client side validation with code 600, etc.) are returned as is
+func wrapError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	kbErr := kbapi.APIError{}
+	if errors.As(err, &kbErr) && kbErr.Code >= 100 && kbErr.Code < 600 {
+		apiErr := NewAPIError(kbErr.Code, "")
+		apiErr.Status = kbErr.Message
+		return apiErr
+	}
+
+	return err
+}
+
+// isRouteNotFound return true if Kibana not know the API route
+// Kibana answer with generic message on unknown route, while it explain which object is missing on known route
+func isRouteNotFound(body string) bool {
+	data := map[string]interface{}{}
+	if err := jsonIterator.ConfigCompatibleWithStandardLibrary.Unmarshal([]byte(body), &data); err != nil {
+		return false
+	}
+
+	return data["message"] == "Not Found"
+}
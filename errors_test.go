@@ -0,0 +1,82 @@
+package kbhandler
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/disaster37/go-kibana-rest/v8/kbapi"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAPIError(t *testing.T) {
+
+	assert.ErrorIs(t, NewAPIError(401, ""), ErrUnauthorized)
+	assert.ErrorIs(t, NewAPIError(403, ""), ErrForbidden)
+	assert.ErrorIs(t, NewAPIError(409, ""), ErrConflict)
+	assert.ErrorIs(t, NewAPIError(404, `{"statusCode": 404, "error": "Not Found", "message": "Saved object [index-pattern/test] not found"}`), ErrNotFound)
+	assert.ErrorIs(t, NewAPIError(404, `{"statusCode": 404, "error": "Not Found", "message": "Not Found"}`), ErrVersionUnsupported)
+	assert.ErrorIs(t, NewAPIError(501, ""), ErrVersionUnsupported)
+
+	err := NewAPIError(500, "Internal Server Error")
+	assert.NotErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, "Kibana answer with status code 500: Internal Server Error", err.Error())
+}
+
+func (t *KibanaHandlerTestSuite) TestErrors() {
+
+	// When object not found
+	httpmock.RegisterResponder("DELETE", urlrole, httpmock.NewStringResponder(404, `{}`))
+	err := t.kbHandler.RoleDelete("test")
+	assert.ErrorIs(t.T(), err, ErrNotFound)
+	apiErr := &APIError{}
+	assert.True(t.T(), errors.As(err, &apiErr))
+	assert.Equal(t.T(), 404, apiErr.StatusCode)
+
+	// When object already exist
+	httpmock.RegisterResponder("POST", fmt.Sprintf("%s/api/spaces/space", baseURL), httpmock.NewStringResponder(409, `{}`))
+	err = t.kbHandler.UserSpaceCreate(&kbapi.KibanaSpace{ID: "test", Name: "test"})
+	assert.ErrorIs(t.T(), err, ErrConflict)
+
+	// When credentials are wrong
+	httpmock.RegisterResponder("GET", urlLogstashPipeline, httpmock.NewStringResponder(401, `{}`))
+	_, err = t.kbHandler.LogstashPipelineGet("test")
+	assert.ErrorIs(t.T(), err, ErrUnauthorized)
+
+	// When user not have privileges
+	httpmock.RegisterResponder("POST", urlAlertingRule+"/_enable", httpmock.NewStringResponder(403, `{"statusCode": 403, "error": "Forbidden", "message": "Unauthorized to enable a rule"}`))
+	err = t.kbHandler.AlertingRuleEnable("test", "test")
+	assert.ErrorIs(t.T(), err, ErrForbidden)
+	assert.ErrorContains(t.T(), err, "Unauthorized to enable a rule")
+
+	// When API not exist on Kibana version
+	httpmock.RegisterResponder("GET", urlDataView, httpmock.NewStringResponder(404, `{"statusCode": 404, "error": "Not Found", "message": "Not Found"}`))
+	_, err = t.kbHandler.DataViewGet("test", "test")
+	assert.ErrorIs(t.T(), err, ErrVersionUnsupported)
+
+	// When error come from go-kibana-rest, the status text is kept
+	httpmock.RegisterResponder("GET", urlrole, httpmock.NewStringResponder(500, `{"message": "boom"}`))
+	_, err = t.kbHandler.RoleGet("test")
+	assert.True(t.T(), errors.As(err, &apiErr))
+	assert.Equal(t.T(), 500, apiErr.StatusCode)
+	assert.Empty(t.T(), apiErr.Body)
+	assert.Equal(t.T(), "Kibana answer with status code 500: "+apiErr.Status, err.Error())
+
+	// When request is done by handler, the body is kept
+	httpmock.RegisterResponder("POST", urlAlertingRule+"/_enable", httpmock.NewStringResponder(500, `{"message": "boom"}`))
+	err = t.kbHandler.AlertingRuleEnable("test", "test")
+	assert.True(t.T(), errors.As(err, &apiErr))
+	assert.Equal(t.T(), `{"message": "boom"}`, apiErr.Body)
+
+	// When go-kibana-rest reject the request before send it, the error is not APIError
+	err = wrapError(kbapi.NewAPIError(600, "You must provide one or more dashboard to import"))
+	assert.False(t.T(), errors.As(err, &apiErr))
+	assert.EqualError(t.T(), err, "You must provide one or more dashboard to import")
+
+	// When network error, the error is returned as is
+	httpmock.RegisterResponder("DELETE", urlrole, httpmock.NewErrorResponder(errors.New("fack error")))
+	err = t.kbHandler.RoleDelete("test")
+	assert.Error(t.T(), err)
+	assert.False(t.T(), errors.As(err, &apiErr))
+}
@@ -0,0 +1,123 @@
+package kbhandler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/disaster37/generic-objectmatcher/patch"
+	jsonIterator "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+)
+
+const (
+	basePathFleetAgentPolicy = "/api/fleet/agent_policies" // Base URL to access on Fleet agent policies
+)
+
+// FleetAgentPolicy is the Fleet agent policy
+// The revision, the status and the package policies are maintained by Fleet, so they are not decoded
+type FleetAgentPolicy struct {
+	ID                 string   `json:"id,omitempty"`
+	Name               string   `json:"name"`
+	Namespace          string   `json:"namespace"`
+	Description        string   `json:"description,omitempty"`
+	MonitoringEnabled  []string `json:"monitoring_enabled"`
+	IsManaged          bool     `json:"is_managed"`
+	DataOutputID       string   `json:"data_output_id,omitempty"`
+	MonitoringOutputID string   `json:"monitoring_output_id,omitempty"`
+	FleetServerHostID  string   `json:"fleet_server_host_id,omitempty"`
+	InactivityTimeout  int      `json:"inactivity_timeout,omitempty"`
+	UnenrollTimeout    int      `json:"unenroll_timeout,omitempty"`
+}
+
+// fleetAgentPolicyResponse is the response of Fleet agent policy API
+type fleetAgentPolicyResponse struct {
+	Item *FleetAgentPolicy `json:"item"`
+}
+
+// FleetAgentPolicyCreate permit to create Fleet agent policy
+// Kibana generate the ID if not provided
+func (h *KibanaHandlerImpl) FleetAgentPolicyCreate(policy *FleetAgentPolicy) (err error) {
+	h.log.Debug("Create Fleet agent policy", "scope", "fleet_agent_policy", "name", policy.Name)
+	h, span := h.startSpan("FleetAgentPolicyCreate", "fleet_agent_policy", policy.Name, "")
+	defer span.end(&err)
+
+	return h.doRequest(http.MethodPost, basePathFleetAgentPolicy, fleetAgentPolicyPayload(policy), nil)
+}
+
+// FleetAgentPolicyUpdate permit to update Fleet agent policy
+func (h *KibanaHandlerImpl) FleetAgentPolicyUpdate(policy *FleetAgentPolicy) (err error) {
+	h.log.Debug("Update Fleet agent policy", "scope", "fleet_agent_policy", "name", policy.ID)
+	h, span := h.startSpan("FleetAgentPolicyUpdate", "fleet_agent_policy", policy.ID, "")
+	defer span.end(&err)
+
+	// Kibana not accept the ID on body
+	payload := fleetAgentPolicyPayload(policy)
+	payload.ID = ""
+
+	return h.doRequest(http.MethodPut, fmt.Sprintf("%s/%s", basePathFleetAgentPolicy, policy.ID), payload, nil)
+}
+
+// FleetAgentPolicyDelete permit to delete Fleet agent policy
+func (h *KibanaHandlerImpl) FleetAgentPolicyDelete(id string) (err error) {
+	h.log.Debug("Delete Fleet agent policy", "scope", "fleet_agent_policy", "name", id)
+	h, span := h.startSpan("FleetAgentPolicyDelete", "fleet_agent_policy", id, "")
+	defer span.end(&err)
+
+	payload := map[string]string{
+		"agentPolicyId": id,
+	}
+
+	return h.doRequest(http.MethodPost, fmt.Sprintf("%s/delete", basePathFleetAgentPolicy), payload, nil)
+}
+
+// FleetAgentPolicyGet permit to get Fleet agent policy
+// It return nil if Fleet agent policy not exist
+func (h *KibanaHandlerImpl) FleetAgentPolicyGet(id string) (policy *FleetAgentPolicy, err error) {
+	h.log.Debug("Get Fleet agent policy", "scope", "fleet_agent_policy", "name", id)
+	h, span := h.startSpan("FleetAgentPolicyGet", "fleet_agent_policy", id, "")
+	defer span.end(&err)
+
+	result := &fleetAgentPolicyResponse{}
+	if err = h.doRequest(http.MethodGet, fmt.Sprintf("%s/%s", basePathFleetAgentPolicy, id), nil, result); err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return result.Item, nil
+}
+
+// FleetAgentPolicyDiff permit to diff Fleet agent policy
+func (h *KibanaHandlerImpl) FleetAgentPolicyDiff(actualObject, expectedObject, originalObject *FleetAgentPolicy) (patchResult *patch.PatchResult, err error) {
+	defer h.observeDiff("fleet_agent_policy", actualObject == nil, &patchResult)
+
+	// If not yet exist
+	if actualObject == nil {
+		expected, err := jsonIterator.ConfigCompatibleWithStandardLibrary.Marshal(expectedObject)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to convert expected object to byte sequence")
+		}
+
+		return &patch.PatchResult{
+			Patch:    expected,
+			Current:  expected,
+			Modified: expected,
+			Original: nil,
+			Patched:  expectedObject,
+		}, nil
+	}
+
+	return patch.DefaultPatchMaker.Calculate(actualObject, expectedObject, originalObject)
+}
+
+// fleetAgentPolicyPayload return copy of Fleet agent policy to send on body
+// Fleet keep the current monitoring when the field is not provided, so the empty list is sent to disable it
+func fleetAgentPolicyPayload(policy *FleetAgentPolicy) *FleetAgentPolicy {
+	payload := *policy
+	if payload.MonitoringEnabled == nil {
+		payload.MonitoringEnabled = []string{}
+	}
+
+	return &payload
+}
@@ -0,0 +1,332 @@
+package kbhandler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var urlFleetAgentPolicy = fmt.Sprintf("%s/api/fleet/agent_policies/test", baseURL)
+
+func (t *KibanaHandlerTestSuite) TestFleetAgentPolicyGet() {
+
+	rawPolicy := `
+{
+	"item": {
+		"id": "test",
+		"name": "test",
+		"namespace": "default",
+		"description": "Agent policy for test",
+		"monitoring_enabled": ["logs", "metrics"],
+		"is_managed": true,
+		"inactivity_timeout": 1209600,
+		"status": "active",
+		"revision": 3,
+		"updated_at": "2023-01-01T00:00:00.000Z",
+		"updated_by": "elastic",
+		"package_policies": []
+	}
+}
+	`
+
+	policyTest := &FleetAgentPolicy{
+		ID:                "test",
+		Name:              "test",
+		Namespace:         "default",
+		Description:       "Agent policy for test",
+		MonitoringEnabled: []string{"logs", "metrics"},
+		IsManaged:         true,
+		InactivityTimeout: 1209600,
+	}
+
+	httpmock.RegisterResponder("GET", urlFleetAgentPolicy, func(req *http.Request) (*http.Response, error) {
+		resp := httpmock.NewStringResponse(200, rawPolicy)
+		return resp, nil
+	})
+
+	policy, err := t.kbHandler.FleetAgentPolicyGet("test")
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Empty(t.T(), cmp.Diff(policyTest, policy))
+
+	// When not found
+	httpmock.RegisterResponder("GET", urlFleetAgentPolicy, httpmock.NewStringResponder(404, `{"statusCode": 404, "error": "Not Found"}`))
+	policy, err = t.kbHandler.FleetAgentPolicyGet("test")
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Nil(t.T(), policy)
+
+	// When error
+	httpmock.RegisterResponder("GET", urlFleetAgentPolicy, httpmock.NewErrorResponder(errors.New("fack error")))
+	_, err = t.kbHandler.FleetAgentPolicyGet("test")
+	assert.Error(t.T(), err)
+}
+
+func (t *KibanaHandlerTestSuite) TestFleetAgentPolicyDelete() {
+
+	url := fmt.Sprintf("%s/api/fleet/agent_policies/delete", baseURL)
+
+	httpmock.RegisterResponder("POST", url, func(req *http.Request) (*http.Response, error) {
+		payload := map[string]interface{}{}
+		if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+			return nil, err
+		}
+		if payload["agentPolicyId"] != "test" {
+			return httpmock.NewStringResponse(400, `{}`), nil
+		}
+		return httpmock.NewStringResponse(200, `{"id": "test", "name": "test"}`), nil
+	})
+
+	err := t.kbHandler.FleetAgentPolicyDelete("test")
+	if err != nil {
+		t.Fail(err.Error())
+	}
+
+	// When error
+	httpmock.RegisterResponder("POST", url, httpmock.NewErrorResponder(errors.New("fack error")))
+	err = t.kbHandler.FleetAgentPolicyDelete("test")
+	assert.Error(t.T(), err)
+}
+
+func (t *KibanaHandlerTestSuite) TestFleetAgentPolicyCreate() {
+
+	rawPolicy := `
+{
+	"id": "test",
+	"name": "test",
+	"namespace": "default",
+	"description": "Agent policy for test",
+	"monitoring_enabled": ["logs", "metrics"],
+	"inactivity_timeout": 1209600
+}
+	`
+
+	policyTest := &FleetAgentPolicy{}
+	if err := json.Unmarshal([]byte(rawPolicy), policyTest); err != nil {
+		panic(err)
+	}
+
+	url := fmt.Sprintf("%s/api/fleet/agent_policies", baseURL)
+
+	httpmock.RegisterResponder("POST", url, func(req *http.Request) (*http.Response, error) {
+		payload := &FleetAgentPolicy{}
+		if err := json.NewDecoder(req.Body).Decode(payload); err != nil {
+			return nil, err
+		}
+		if payload.Name == "" || payload.Namespace == "" {
+			return httpmock.NewStringResponse(400, `{}`), nil
+		}
+		return httpmock.NewStringResponse(200, `{"item": {"id": "test", "name": "test", "namespace": "default"}}`), nil
+	})
+
+	err := t.kbHandler.FleetAgentPolicyCreate(policyTest)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+
+	// When error
+	httpmock.RegisterResponder("POST", url, httpmock.NewErrorResponder(errors.New("fack error")))
+	err = t.kbHandler.FleetAgentPolicyCreate(policyTest)
+	assert.Error(t.T(), err)
+}
+
+func (t *KibanaHandlerTestSuite) TestFleetAgentPolicyUpdate() {
+
+	rawPolicy := `
+{
+	"id": "test",
+	"name": "test",
+	"namespace": "default",
+	"description": "Agent policy for test",
+	"monitoring_enabled": ["logs", "metrics"],
+	"inactivity_timeout": 1209600
+}
+	`
+
+	policyTest := &FleetAgentPolicy{}
+	if err := json.Unmarshal([]byte(rawPolicy), policyTest); err != nil {
+		panic(err)
+	}
+
+	httpmock.RegisterResponder("PUT", urlFleetAgentPolicy, func(req *http.Request) (*http.Response, error) {
+		payload := map[string]interface{}{}
+		if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+			return nil, err
+		}
+		if _, ok := payload["id"]; ok {
+			return httpmock.NewStringResponse(400, `{}`), nil
+		}
+		return httpmock.NewStringResponse(200, `{"item": {"id": "test", "name": "test", "namespace": "default"}}`), nil
+	})
+
+	err := t.kbHandler.FleetAgentPolicyUpdate(policyTest)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+
+	// When monitoring is disabled and policy is not managed anymore
+	rawPolicy = `
+{
+	"id": "test",
+	"name": "test",
+	"namespace": "default"
+}
+	`
+	policyTest = &FleetAgentPolicy{}
+	if err := json.Unmarshal([]byte(rawPolicy), policyTest); err != nil {
+		panic(err)
+	}
+	var payload map[string]interface{}
+	httpmock.RegisterResponder("PUT", urlFleetAgentPolicy, func(req *http.Request) (*http.Response, error) {
+		if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+			return nil, err
+		}
+		return httpmock.NewStringResponse(200, `{"item": {"id": "test", "name": "test", "namespace": "default"}}`), nil
+	})
+	err = t.kbHandler.FleetAgentPolicyUpdate(policyTest)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Equal(t.T(), false, payload["is_managed"])
+	assert.Equal(t.T(), []interface{}{}, payload["monitoring_enabled"])
+
+	// When error
+	httpmock.RegisterResponder("PUT", urlFleetAgentPolicy, httpmock.NewErrorResponder(errors.New("fack error")))
+	err = t.kbHandler.FleetAgentPolicyUpdate(policyTest)
+	assert.Error(t.T(), err)
+}
+
+func (t *KibanaHandlerTestSuite) TestFleetAgentPolicyDiff() {
+	var actual, expected, original *FleetAgentPolicy
+
+	rawPolicy := `
+{
+	"id": "test",
+	"name": "test",
+	"namespace": "default",
+	"description": "Agent policy for test",
+	"monitoring_enabled": ["logs", "metrics"],
+	"is_managed": true,
+	"inactivity_timeout": 1209600
+}
+	`
+
+	expected = &FleetAgentPolicy{}
+	if err := json.Unmarshal([]byte(rawPolicy), expected); err != nil {
+		panic(err)
+	}
+
+	// When Fleet agent policy not exist yet
+	actual = nil
+	diff, err := t.kbHandler.FleetAgentPolicyDiff(actual, expected, nil)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.False(t.T(), diff.IsEmpty())
+	assert.Equal(t.T(), expected, diff.Patched)
+
+	// When Fleet agent policy is the same
+	actual = &FleetAgentPolicy{}
+	if err := json.Unmarshal([]byte(rawPolicy), actual); err != nil {
+		panic(err)
+	}
+	diff, err = t.kbHandler.FleetAgentPolicyDiff(actual, expected, actual)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.True(t.T(), diff.IsEmpty())
+	assert.Equal(t.T(), expected, diff.Patched)
+
+	// When Fleet agent policy is not the same
+	rawPolicy = `
+{
+	"id": "test",
+	"name": "test",
+	"namespace": "production",
+	"description": "Agent policy for test",
+	"monitoring_enabled": ["logs"],
+	"is_managed": true,
+	"inactivity_timeout": 1209600
+}
+	`
+	expected = &FleetAgentPolicy{}
+	if err := json.Unmarshal([]byte(rawPolicy), expected); err != nil {
+		panic(err)
+	}
+	diff, err = t.kbHandler.FleetAgentPolicyDiff(actual, expected, actual)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.False(t.T(), diff.IsEmpty())
+	assert.Equal(t.T(), expected, diff.Patched)
+
+	// When kibana add default values
+	rawActual := `
+{
+	"id": "test",
+	"name": "test",
+	"namespace": "production",
+	"description": "Agent policy for test",
+	"monitoring_enabled": ["logs"],
+	"is_managed": true,
+	"inactivity_timeout": 1209600,
+	"data_output_id": "default-output"
+}
+	`
+	actual = &FleetAgentPolicy{}
+	if err := json.Unmarshal([]byte(rawActual), actual); err != nil {
+		panic(err)
+	}
+	original = &FleetAgentPolicy{}
+	if err := json.Unmarshal([]byte(rawPolicy), original); err != nil {
+		panic(err)
+	}
+	diff, err = t.kbHandler.FleetAgentPolicyDiff(actual, expected, original)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.True(t.T(), diff.IsEmpty())
+	assert.Equal(t.T(), actual, diff.Patched)
+
+	// When monitoring is disabled, Fleet return the empty list
+	rawPolicy = `
+{
+	"id": "test",
+	"name": "test",
+	"namespace": "production"
+}
+	`
+	rawActual = `
+{
+	"id": "test",
+	"name": "test",
+	"namespace": "production",
+	"monitoring_enabled": [],
+	"is_managed": false
+}
+	`
+	expected = &FleetAgentPolicy{}
+	if err := json.Unmarshal([]byte(rawPolicy), expected); err != nil {
+		panic(err)
+	}
+	original = &FleetAgentPolicy{}
+	if err := json.Unmarshal([]byte(rawPolicy), original); err != nil {
+		panic(err)
+	}
+	actual = &FleetAgentPolicy{}
+	if err := json.Unmarshal([]byte(rawActual), actual); err != nil {
+		panic(err)
+	}
+	diff, err = t.kbHandler.FleetAgentPolicyDiff(actual, expected, original)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.True(t.T(), diff.IsEmpty())
+}
@@ -0,0 +1,426 @@
+package kbhandler
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/disaster37/generic-objectmatcher/patch"
+	jsonIterator "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+)
+
+const (
+	basePathFleetPackagePolicy = "/api/fleet/package_policies" // Base URL to access on Fleet package policies
+	basePathFleetPackage       = "/api/fleet/epm/packages"     // Base URL to access on Fleet packages
+)
+
+// FleetPackagePolicy is the Fleet package policy (integration attached on agent policy)
+type FleetPackagePolicy struct {
+	ID          string                           `json:"id,omitempty"`
+	Name        string                           `json:"name"`
+	Namespace   string                           `json:"namespace,omitempty"`
+	Description string                           `json:"description,omitempty"`
+	PolicyID    string                           `json:"policy_id"`
+	Enabled     *bool                            `json:"enabled,omitempty"`
+	Package     *FleetPackagePolicyPackage       `json:"package,omitempty"`
+	Inputs      []FleetPackagePolicyInput        `json:"inputs"`
+	Vars        map[string]FleetPackagePolicyVar `json:"vars,omitempty"`
+}
+
+// FleetPackagePolicyPackage is the integration package used by package policy
+type FleetPackagePolicyPackage struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Title   string `json:"title,omitempty"`
+}
+
+// FleetPackagePolicyInput is the input of package policy
+type FleetPackagePolicyInput struct {
+	Type           string                           `json:"type"`
+	PolicyTemplate string                           `json:"policy_template,omitempty"`
+	Enabled        bool                             `json:"enabled"`
+	Vars           map[string]FleetPackagePolicyVar `json:"vars,omitempty"`
+	Streams        []FleetPackagePolicyInputStream  `json:"streams"`
+}
+
+// FleetPackagePolicyInputStream is the data stream collected by the input
+type FleetPackagePolicyInputStream struct {
+	ID         string                           `json:"id,omitempty"`
+	Enabled    bool                             `json:"enabled"`
+	DataStream FleetPackagePolicyDataStream     `json:"data_stream"`
+	Vars       map[string]FleetPackagePolicyVar `json:"vars,omitempty"`
+}
+
+// FleetPackagePolicyDataStream is the data stream targeted by the stream
+type FleetPackagePolicyDataStream struct {
+	Type    string `json:"type,omitempty"`
+	Dataset string `json:"dataset"`
+}
+
+// FleetPackagePolicyVar is the variable of package policy, input or stream
+type FleetPackagePolicyVar struct {
+	Value  interface{} `json:"value"`
+	Type   string      `json:"type,omitempty"`
+	Frozen bool        `json:"frozen,omitempty"`
+}
+
+// fleetPackagePolicyResponse is the response of Fleet package policy API
+type fleetPackagePolicyResponse struct {
+	Item *FleetPackagePolicy `json:"item"`
+}
+
+// fleetPackageResponse is the response of Fleet package API
+type fleetPackageResponse struct {
+	Item struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+		Status  string `json:"status"`
+
+		// The installed version, that can be different from the version read
+		SavedObject *struct {
+			Attributes struct {
+				Version string `json:"version"`
+			} `json:"attributes"`
+		} `json:"savedObject,omitempty"`
+		InstallationInfo *struct {
+			Version string `json:"version"`
+		} `json:"installationInfo,omitempty"`
+	} `json:"item"`
+}
+
+// installedVersion return the version of package installed on Fleet, or empty string if not installed
+func (r *fleetPackageResponse) installedVersion() string {
+	if r.Item.Status != "installed" {
+		return ""
+	}
+	switch {
+	case r.Item.InstallationInfo != nil && r.Item.InstallationInfo.Version != "":
+		return r.Item.InstallationInfo.Version
+	case r.Item.SavedObject != nil && r.Item.SavedObject.Attributes.Version != "":
+		return r.Item.SavedObject.Attributes.Version
+	default:
+		return r.Item.Version
+	}
+}
+
+// FleetPackagePolicyCreate permit to create Fleet package policy
+// Kibana generate the ID if not provided
+func (h *KibanaHandlerImpl) FleetPackagePolicyCreate(policy *FleetPackagePolicy) (err error) {
+	h.log.Debug("Create Fleet package policy", "scope", "fleet_package_policy", "name", policy.Name)
+	h, span := h.startSpan("FleetPackagePolicyCreate", "fleet_package_policy", policy.Name, "")
+	defer span.end(&err)
+
+	return h.doRequest(http.MethodPost, basePathFleetPackagePolicy, policy, nil)
+}
+
+// FleetPackagePolicyUpdate permit to update Fleet package policy
+func (h *KibanaHandlerImpl) FleetPackagePolicyUpdate(policy *FleetPackagePolicy) (err error) {
+	h.log.Debug("Update Fleet package policy", "scope", "fleet_package_policy", "name", policy.ID)
+	h, span := h.startSpan("FleetPackagePolicyUpdate", "fleet_package_policy", policy.ID, "")
+	defer span.end(&err)
+
+	// Kibana not accept the ID on body
+	payload := *policy
+	payload.ID = ""
+
+	return h.doRequest(http.MethodPut, fmt.Sprintf("%s/%s", basePathFleetPackagePolicy, policy.ID), &payload, nil)
+}
+
+// FleetPackagePolicyDelete permit to delete Fleet package policy
+func (h *KibanaHandlerImpl) FleetPackagePolicyDelete(id string) (err error) {
+	h.log.Debug("Delete Fleet package policy", "scope", "fleet_package_policy", "name", id)
+	h, span := h.startSpan("FleetPackagePolicyDelete", "fleet_package_policy", id, "")
+	defer span.end(&err)
+
+	return h.doRequest(http.MethodDelete, fmt.Sprintf("%s/%s", basePathFleetPackagePolicy, id), nil, nil)
+}
+
+// FleetPackagePolicyGet permit to get Fleet package policy
+// It return nil if Fleet package policy not exist
+func (h *KibanaHandlerImpl) FleetPackagePolicyGet(id string) (policy *FleetPackagePolicy, err error) {
+	h.log.Debug("Get Fleet package policy", "scope", "fleet_package_policy", "name", id)
+	h, span := h.startSpan("FleetPackagePolicyGet", "fleet_package_policy", id, "")
+	defer span.end(&err)
+
+	result := &fleetPackagePolicyResponse{}
+	if err = h.doRequest(http.MethodGet, fmt.Sprintf("%s/%s", basePathFleetPackagePolicy, id), nil, result); err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return result.Item, nil
+}
+
+// FleetPackagePolicyDiff permit to diff Fleet package policy
+// Kibana rewrite inputs and streams on save (order, stream IDs, disabled inputs, var types), so they are normalized before diff
+// The patched object is built from the actual object, so it keep the stream IDs, the var types and the disabled inputs
+func (h *KibanaHandlerImpl) FleetPackagePolicyDiff(actualObject, expectedObject, originalObject *FleetPackagePolicy) (patchResult *patch.PatchResult, err error) {
+	defer h.observeDiff("fleet_package_policy", actualObject == nil, &patchResult)
+
+	// If not yet exist
+	if actualObject == nil {
+		expected, err := jsonIterator.ConfigCompatibleWithStandardLibrary.Marshal(expectedObject)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to convert expected object to byte sequence")
+		}
+
+		return &patch.PatchResult{
+			Patch:    expected,
+			Current:  expected,
+			Modified: expected,
+			Original: nil,
+			Patched:  expectedObject,
+		}, nil
+	}
+
+	actual, err := normalizeFleetPackagePolicy(actualObject, expectedObject)
+	if err != nil {
+		return nil, err
+	}
+	expected, err := normalizeFleetPackagePolicy(expectedObject, nil)
+	if err != nil {
+		return nil, err
+	}
+	original, err := normalizeFleetPackagePolicy(originalObject, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	patchResult, err = patch.DefaultPatchMaker.Calculate(actual, expected, original)
+	if err != nil {
+		return nil, err
+	}
+
+	// The normalization is only needed to compare, so the patched object is built from the object returned by Fleet
+	patchResult.Patched = patchFleetPackagePolicy(actualObject, patchResult.Patched.(*FleetPackagePolicy), expectedObject)
+
+	return patchResult, nil
+}
+
+// EnsurePackageInstalled permit to install the integration package if not yet installed
+// The package is upgraded or downgraded when another version is installed
+func (h *KibanaHandlerImpl) EnsurePackageInstalled(name, version string) (err error) {
+	h.log.Debug("Ensure Fleet package is installed", "scope", "fleet_package", "name", name, "version", version)
+	h, span := h.startSpan("EnsurePackageInstalled", "fleet_package", name, "")
+	defer span.end(&err)
+
+	path := fmt.Sprintf("%s/%s/%s", basePathFleetPackage, name, version)
+	result := &fleetPackageResponse{}
+	if err = h.doRequest(http.MethodGet, path, nil, result); err != nil {
+		return err
+	}
+	installedVersion := result.installedVersion()
+	if installedVersion == version {
+		return nil
+	}
+
+	h.log.Debug("Install Fleet package", "scope", "fleet_package", "name", name, "version", version, "installedVersion", installedVersion)
+	return h.doRequest(http.MethodPost, path, nil, nil)
+}
+
+// normalizeFleetPackagePolicy return copy of package policy normalized to be diffed
+// When reference is provided, the disabled inputs and streams not declared on it are removed
+func normalizeFleetPackagePolicy(policy, reference *FleetPackagePolicy) (*FleetPackagePolicy, error) {
+	if policy == nil {
+		return nil, nil
+	}
+
+	// Deep copy to not modify the provided object
+	b, err := jsonIterator.ConfigCompatibleWithStandardLibrary.Marshal(policy)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to convert package policy to byte sequence")
+	}
+	p := &FleetPackagePolicy{}
+	if err = jsonIterator.ConfigCompatibleWithStandardLibrary.Unmarshal(b, p); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode package policy")
+	}
+
+	declaredInputs, declaredStreams, declaredVars := fleetPackagePolicyDeclared(reference)
+
+	if p.Package != nil {
+		p.Package.Title = ""
+	}
+	if reference != nil {
+		trimFleetPackagePolicyVars(p.Vars, declaredVars[""])
+	}
+	normalizeFleetPackagePolicyVars(p.Vars)
+
+	inputs := make([]FleetPackagePolicyInput, 0, len(p.Inputs))
+	for _, input := range p.Inputs {
+		inputKey := input.Type + "/" + input.PolicyTemplate
+		if reference != nil && !input.Enabled && !declaredInputs[inputKey] {
+			continue
+		}
+		if declaredInputs[inputKey] {
+			trimFleetPackagePolicyVars(input.Vars, declaredVars["input/"+inputKey])
+		}
+		normalizeFleetPackagePolicyVars(input.Vars)
+
+		streams := make([]FleetPackagePolicyInputStream, 0, len(input.Streams))
+		for _, stream := range input.Streams {
+			if reference != nil && !stream.Enabled && !declaredStreams[inputKey+"/"+stream.DataStream.Dataset] {
+				continue
+			}
+			stream.ID = ""
+			if streamKey := inputKey + "/" + stream.DataStream.Dataset; declaredStreams[streamKey] {
+				trimFleetPackagePolicyVars(stream.Vars, declaredVars["stream/"+streamKey])
+			}
+			normalizeFleetPackagePolicyVars(stream.Vars)
+			streams = append(streams, stream)
+		}
+		sort.SliceStable(streams, func(i, j int) bool {
+			return streams[i].DataStream.Dataset < streams[j].DataStream.Dataset
+		})
+		input.Streams = streams
+
+		inputs = append(inputs, input)
+	}
+	sort.SliceStable(inputs, func(i, j int) bool {
+		if inputs[i].Type == inputs[j].Type {
+			return inputs[i].PolicyTemplate < inputs[j].PolicyTemplate
+		}
+		return inputs[i].Type < inputs[j].Type
+	})
+	p.Inputs = inputs
+
+	return p, nil
+}
+
+// normalizeFleetPackagePolicyVars remove the var type and frozen flag computed by Kibana from package definition
+func normalizeFleetPackagePolicyVars(vars map[string]FleetPackagePolicyVar) {
+	for key, v := range vars {
+		vars[key] = FleetPackagePolicyVar{Value: v.Value}
+	}
+}
+
+// trimFleetPackagePolicyVars remove the vars not declared, like the package default vars added by Fleet
+func trimFleetPackagePolicyVars(vars map[string]FleetPackagePolicyVar, declared map[string]bool) {
+	for key := range vars {
+		if !declared[key] {
+			delete(vars, key)
+		}
+	}
+}
+
+// fleetPackagePolicyDeclared return the keys of inputs, streams and vars declared on package policy
+// The vars are indexed by "" for the policy, by "input/<input key>" for inputs and by "stream/<stream key>" for streams
+func fleetPackagePolicyDeclared(policy *FleetPackagePolicy) (inputs map[string]bool, streams map[string]bool, vars map[string]map[string]bool) {
+	inputs = map[string]bool{}
+	streams = map[string]bool{}
+	vars = map[string]map[string]bool{}
+	if policy == nil {
+		return inputs, streams, vars
+	}
+
+	varKeys := func(v map[string]FleetPackagePolicyVar) map[string]bool {
+		keys := make(map[string]bool, len(v))
+		for key := range v {
+			keys[key] = true
+		}
+		return keys
+	}
+
+	vars[""] = varKeys(policy.Vars)
+	for _, input := range policy.Inputs {
+		inputKey := input.Type + "/" + input.PolicyTemplate
+		inputs[inputKey] = true
+		vars["input/"+inputKey] = varKeys(input.Vars)
+		for _, stream := range input.Streams {
+			streamKey := inputKey + "/" + stream.DataStream.Dataset
+			streams[streamKey] = true
+			vars["stream/"+streamKey] = varKeys(stream.Vars)
+		}
+	}
+
+	return inputs, streams, vars
+}
+
+// patchFleetPackagePolicy apply the normalized patched package policy on copy of the package policy returned by Fleet
+// It keep the stream IDs, the var types and the disabled inputs and streams removed by the normalization
+func patchFleetPackagePolicy(actual, patched, reference *FleetPackagePolicy) *FleetPackagePolicy {
+	declaredInputs, declaredStreams, _ := fleetPackagePolicyDeclared(reference)
+
+	p := *patched
+	if p.Package != nil && actual.Package != nil && p.Package.Name == actual.Package.Name {
+		pkg := *p.Package
+		pkg.Title = actual.Package.Title
+		p.Package = &pkg
+	}
+	p.Vars = patchFleetPackagePolicyVars(actual.Vars, patched.Vars)
+
+	actualInputs := map[string]FleetPackagePolicyInput{}
+	for _, input := range actual.Inputs {
+		actualInputs[input.Type+"/"+input.PolicyTemplate] = input
+	}
+
+	p.Inputs = make([]FleetPackagePolicyInput, 0, len(actual.Inputs))
+	patchedInputs := map[string]bool{}
+	for _, input := range patched.Inputs {
+		inputKey := input.Type + "/" + input.PolicyTemplate
+		patchedInputs[inputKey] = true
+		actualInput, isExist := actualInputs[inputKey]
+		if !isExist {
+			p.Inputs = append(p.Inputs, input)
+			continue
+		}
+
+		input.Vars = patchFleetPackagePolicyVars(actualInput.Vars, input.Vars)
+
+		actualStreams := map[string]FleetPackagePolicyInputStream{}
+		for _, stream := range actualInput.Streams {
+			actualStreams[stream.DataStream.Dataset] = stream
+		}
+		streams := make([]FleetPackagePolicyInputStream, 0, len(actualInput.Streams))
+		patchedStreams := map[string]bool{}
+		for _, stream := range input.Streams {
+			patchedStreams[stream.DataStream.Dataset] = true
+			if actualStream, isExist := actualStreams[stream.DataStream.Dataset]; isExist {
+				stream.ID = actualStream.ID
+				stream.Vars = patchFleetPackagePolicyVars(actualStream.Vars, stream.Vars)
+			}
+			streams = append(streams, stream)
+		}
+		for _, stream := range actualInput.Streams {
+			if !patchedStreams[stream.DataStream.Dataset] && !stream.Enabled && !declaredStreams[inputKey+"/"+stream.DataStream.Dataset] {
+				streams = append(streams, stream)
+			}
+		}
+		input.Streams = streams
+
+		p.Inputs = append(p.Inputs, input)
+	}
+	for _, input := range actual.Inputs {
+		inputKey := input.Type + "/" + input.PolicyTemplate
+		if !patchedInputs[inputKey] && !input.Enabled && !declaredInputs[inputKey] {
+			p.Inputs = append(p.Inputs, input)
+		}
+	}
+
+	return &p
+}
+
+// patchFleetPackagePolicyVars apply the patched var values and keep the var type and frozen flag computed by Kibana
+// The vars not declared, removed by the normalization, are kept
+func patchFleetPackagePolicyVars(actual, patched map[string]FleetPackagePolicyVar) map[string]FleetPackagePolicyVar {
+	if patched == nil && actual == nil {
+		return nil
+	}
+
+	vars := make(map[string]FleetPackagePolicyVar, len(actual))
+	for key, v := range actual {
+		vars[key] = v
+	}
+	for key, v := range patched {
+		if actualVar, isExist := actual[key]; isExist {
+			actualVar.Value = v.Value
+			vars[key] = actualVar
+			continue
+		}
+		vars[key] = v
+	}
+
+	return vars
+}
@@ -0,0 +1,576 @@
+package kbhandler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var urlFleetPackagePolicy = fmt.Sprintf("%s/api/fleet/package_policies/test", baseURL)
+
+func (t *KibanaHandlerTestSuite) TestFleetPackagePolicyGet() {
+
+	rawPolicy := `
+{
+	"item": {
+		"id": "test",
+		"name": "system-1",
+		"namespace": "default",
+		"policy_id": "agent-policy",
+		"enabled": true,
+		"revision": 2,
+		"created_at": "2023-01-01T00:00:00.000Z",
+		"package": {
+			"name": "system",
+			"title": "System",
+			"version": "1.20.4"
+		},
+		"inputs": [
+			{
+				"type": "system/metrics",
+				"policy_template": "system",
+				"enabled": false,
+				"streams": [
+					{
+						"id": "system/metrics-system.cpu-test",
+						"enabled": false,
+						"data_stream": {
+							"type": "metrics",
+							"dataset": "system.cpu"
+						}
+					}
+				]
+			},
+			{
+				"type": "logfile",
+				"policy_template": "system",
+				"enabled": true,
+				"streams": [
+					{
+						"id": "logfile-system.syslog-test",
+						"enabled": true,
+						"data_stream": {
+							"type": "logs",
+							"dataset": "system.syslog"
+						},
+						"vars": {
+							"paths": {
+								"type": "text",
+								"value": ["/var/log/messages"]
+							}
+						},
+						"compiled_stream": {
+							"paths": ["/var/log/messages"]
+						}
+					},
+					{
+						"id": "logfile-system.auth-test",
+						"enabled": true,
+						"data_stream": {
+							"type": "logs",
+							"dataset": "system.auth"
+						},
+						"vars": {
+							"paths": {
+								"type": "text",
+								"value": ["/var/log/auth.log"]
+							}
+						},
+						"compiled_stream": {
+							"paths": ["/var/log/auth.log"]
+						}
+					}
+				]
+			}
+		]
+	}
+}
+	`
+
+	httpmock.RegisterResponder("GET", urlFleetPackagePolicy, func(req *http.Request) (*http.Response, error) {
+		resp := httpmock.NewStringResponse(200, rawPolicy)
+		return resp, nil
+	})
+
+	policy, err := t.kbHandler.FleetPackagePolicyGet("test")
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Equal(t.T(), "test", policy.ID)
+	assert.Equal(t.T(), "System", policy.Package.Title)
+	assert.Len(t.T(), policy.Inputs, 2)
+
+	// When not found
+	httpmock.RegisterResponder("GET", urlFleetPackagePolicy, httpmock.NewStringResponder(404, `{"statusCode": 404, "error": "Not Found"}`))
+	policy, err = t.kbHandler.FleetPackagePolicyGet("test")
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Nil(t.T(), policy)
+
+	// When error
+	httpmock.RegisterResponder("GET", urlFleetPackagePolicy, httpmock.NewErrorResponder(errors.New("fack error")))
+	_, err = t.kbHandler.FleetPackagePolicyGet("test")
+	assert.Error(t.T(), err)
+}
+
+func (t *KibanaHandlerTestSuite) TestFleetPackagePolicyDelete() {
+
+	httpmock.RegisterResponder("DELETE", urlFleetPackagePolicy, httpmock.NewStringResponder(200, `{"id": "test"}`))
+
+	err := t.kbHandler.FleetPackagePolicyDelete("test")
+	if err != nil {
+		t.Fail(err.Error())
+	}
+
+	// When error
+	httpmock.RegisterResponder("DELETE", urlFleetPackagePolicy, httpmock.NewErrorResponder(errors.New("fack error")))
+	err = t.kbHandler.FleetPackagePolicyDelete("test")
+	assert.Error(t.T(), err)
+}
+
+func (t *KibanaHandlerTestSuite) TestFleetPackagePolicyCreate() {
+
+	rawPolicy := `
+{
+	"name": "system-1",
+	"namespace": "default",
+	"policy_id": "agent-policy",
+	"package": {
+		"name": "system",
+		"version": "1.20.4"
+	},
+	"inputs": [
+		{
+			"type": "logfile",
+			"policy_template": "system",
+			"enabled": true,
+			"streams": [
+				{
+					"enabled": true,
+					"data_stream": {
+						"type": "logs",
+						"dataset": "system.auth"
+					},
+					"vars": {
+						"paths": {
+							"value": ["/var/log/auth.log"]
+						}
+					}
+				},
+				{
+					"enabled": true,
+					"data_stream": {
+						"type": "logs",
+						"dataset": "system.syslog"
+					},
+					"vars": {
+						"paths": {
+							"value": ["/var/log/messages"]
+						}
+					}
+				}
+			]
+		}
+	]
+}
+	`
+
+	policyTest := &FleetPackagePolicy{}
+	if err := json.Unmarshal([]byte(rawPolicy), policyTest); err != nil {
+		panic(err)
+	}
+
+	rawResponse := `
+{
+	"item": {
+		"id": "test",
+		"name": "system-1",
+		"namespace": "default",
+		"policy_id": "agent-policy",
+		"revision": 1
+	}
+}
+	`
+
+	url := fmt.Sprintf("%s/api/fleet/package_policies", baseURL)
+
+	httpmock.RegisterResponder("POST", url, func(req *http.Request) (*http.Response, error) {
+		payload := &FleetPackagePolicy{}
+		if err := json.NewDecoder(req.Body).Decode(payload); err != nil {
+			return nil, err
+		}
+		if payload.PolicyID == "" || payload.Package == nil {
+			return httpmock.NewStringResponse(400, `{}`), nil
+		}
+		return httpmock.NewStringResponse(200, rawResponse), nil
+	})
+
+	err := t.kbHandler.FleetPackagePolicyCreate(policyTest)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+
+	// When error
+	httpmock.RegisterResponder("POST", url, httpmock.NewErrorResponder(errors.New("fack error")))
+	err = t.kbHandler.FleetPackagePolicyCreate(policyTest)
+	assert.Error(t.T(), err)
+}
+
+func (t *KibanaHandlerTestSuite) TestFleetPackagePolicyUpdate() {
+
+	rawPolicy := `
+{
+	"id": "test",
+	"name": "system-1",
+	"namespace": "default",
+	"policy_id": "agent-policy",
+	"package": {
+		"name": "system",
+		"version": "1.20.4"
+	},
+	"inputs": [
+		{
+			"type": "logfile",
+			"policy_template": "system",
+			"enabled": true,
+			"streams": [
+				{
+					"enabled": true,
+					"data_stream": {
+						"type": "logs",
+						"dataset": "system.auth"
+					},
+					"vars": {
+						"paths": {
+							"value": ["/var/log/auth.log"]
+						}
+					}
+				},
+				{
+					"enabled": true,
+					"data_stream": {
+						"type": "logs",
+						"dataset": "system.syslog"
+					},
+					"vars": {
+						"paths": {
+							"value": ["/var/log/messages"]
+						}
+					}
+				}
+			]
+		}
+	]
+}
+	`
+
+	policyTest := &FleetPackagePolicy{}
+	if err := json.Unmarshal([]byte(rawPolicy), policyTest); err != nil {
+		panic(err)
+	}
+
+	rawResponse := `
+{
+	"item": {
+		"id": "test",
+		"name": "system-1",
+		"namespace": "default",
+		"policy_id": "agent-policy",
+		"revision": 1
+	}
+}
+	`
+
+	httpmock.RegisterResponder("PUT", urlFleetPackagePolicy, func(req *http.Request) (*http.Response, error) {
+		payload := map[string]interface{}{}
+		if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+			return nil, err
+		}
+		if _, ok := payload["id"]; ok {
+			return httpmock.NewStringResponse(400, `{}`), nil
+		}
+		return httpmock.NewStringResponse(200, rawResponse), nil
+	})
+
+	err := t.kbHandler.FleetPackagePolicyUpdate(policyTest)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+
+	// When error
+	httpmock.RegisterResponder("PUT", urlFleetPackagePolicy, httpmock.NewErrorResponder(errors.New("fack error")))
+	err = t.kbHandler.FleetPackagePolicyUpdate(policyTest)
+	assert.Error(t.T(), err)
+}
+
+func (t *KibanaHandlerTestSuite) TestFleetPackagePolicyDiff() {
+	var actual, expected, original *FleetPackagePolicy
+
+	rawPolicy := `
+{
+	"name": "system-1",
+	"namespace": "default",
+	"policy_id": "agent-policy",
+	"package": {
+		"name": "system",
+		"version": "1.20.4"
+	},
+	"inputs": [
+		{
+			"type": "logfile",
+			"policy_template": "system",
+			"enabled": true,
+			"streams": [
+				{
+					"enabled": true,
+					"data_stream": {
+						"type": "logs",
+						"dataset": "system.auth"
+					},
+					"vars": {
+						"paths": {
+							"value": ["/var/log/auth.log"]
+						}
+					}
+				},
+				{
+					"enabled": true,
+					"data_stream": {
+						"type": "logs",
+						"dataset": "system.syslog"
+					},
+					"vars": {
+						"paths": {
+							"value": ["/var/log/messages"]
+						}
+					}
+				}
+			]
+		}
+	]
+}
+	`
+
+	expected = &FleetPackagePolicy{}
+	if err := json.Unmarshal([]byte(rawPolicy), expected); err != nil {
+		panic(err)
+	}
+
+	// When Fleet package policy not exist yet
+	actual = nil
+	diff, err := t.kbHandler.FleetPackagePolicyDiff(actual, expected, nil)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.False(t.T(), diff.IsEmpty())
+	assert.Equal(t.T(), expected, diff.Patched)
+
+	// When Fleet package policy is the same, but rewritten by Kibana with the package default vars
+	rawActual := `
+{
+	"item": {
+		"id": "test",
+		"name": "system-1",
+		"namespace": "default",
+		"policy_id": "agent-policy",
+		"enabled": true,
+		"revision": 2,
+		"created_at": "2023-01-01T00:00:00.000Z",
+		"package": {
+			"name": "system",
+			"title": "System",
+			"version": "1.20.4"
+		},
+		"inputs": [
+			{
+				"type": "system/metrics",
+				"policy_template": "system",
+				"enabled": false,
+				"streams": [
+					{
+						"id": "system/metrics-system.cpu-test",
+						"enabled": false,
+						"data_stream": {
+							"type": "metrics",
+							"dataset": "system.cpu"
+						}
+					}
+				]
+			},
+			{
+				"type": "logfile",
+				"policy_template": "system",
+				"enabled": true,
+				"streams": [
+					{
+						"id": "logfile-system.syslog-test",
+						"enabled": true,
+						"data_stream": {
+							"type": "logs",
+							"dataset": "system.syslog"
+						},
+						"vars": {
+							"paths": {
+								"type": "text",
+								"value": ["/var/log/messages"]
+							},
+							"tags": {
+								"type": "text",
+								"value": ["forwarded"]
+							},
+							"preserve_original_event": {
+								"type": "bool",
+								"value": false
+							}
+						},
+						"compiled_stream": {
+							"paths": ["/var/log/messages"]
+						}
+					},
+					{
+						"id": "logfile-system.auth-test",
+						"enabled": true,
+						"data_stream": {
+							"type": "logs",
+							"dataset": "system.auth"
+						},
+						"vars": {
+							"paths": {
+								"type": "text",
+								"value": ["/var/log/auth.log"]
+							},
+							"tags": {
+								"type": "text",
+								"value": ["forwarded"]
+							}
+						},
+						"compiled_stream": {
+							"paths": ["/var/log/auth.log"]
+						}
+					}
+				]
+			}
+		]
+	}
+}
+	`
+	response := &fleetPackagePolicyResponse{}
+	if err := json.Unmarshal([]byte(rawActual), response); err != nil {
+		panic(err)
+	}
+	actual = response.Item
+	original = &FleetPackagePolicy{}
+	if err := json.Unmarshal([]byte(rawPolicy), original); err != nil {
+		panic(err)
+	}
+	diff, err = t.kbHandler.FleetPackagePolicyDiff(actual, expected, original)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.True(t.T(), diff.IsEmpty())
+
+	// Objects provided are not modified by the normalization
+	assert.Len(t.T(), actual.Inputs, 2)
+	assert.Empty(t.T(), cmp.Diff(original, expected))
+
+	// When Fleet package policy is not the same
+	expected.Inputs[0].Streams[1].Vars["paths"] = FleetPackagePolicyVar{Value: []interface{}{"/var/log/syslog"}}
+	diff, err = t.kbHandler.FleetPackagePolicyDiff(actual, expected, original)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.False(t.T(), diff.IsEmpty())
+
+	// Patched keep the data computed by Fleet
+	patched := diff.Patched.(*FleetPackagePolicy)
+	assert.Equal(t.T(), "System", patched.Package.Title)
+	assert.Len(t.T(), patched.Inputs, 2)
+	assert.Equal(t.T(), "logfile", patched.Inputs[0].Type)
+	assert.Equal(t.T(), "logfile-system.syslog-test", patched.Inputs[0].Streams[1].ID)
+	assert.Equal(t.T(), FleetPackagePolicyVar{Type: "text", Value: []interface{}{"/var/log/syslog"}}, patched.Inputs[0].Streams[1].Vars["paths"])
+	assert.Equal(t.T(), FleetPackagePolicyVar{Type: "bool", Value: false}, patched.Inputs[0].Streams[1].Vars["preserve_original_event"])
+	assert.Equal(t.T(), "system/metrics", patched.Inputs[1].Type)
+	assert.False(t.T(), patched.Inputs[1].Enabled)
+	assert.Equal(t.T(), "system/metrics-system.cpu-test", patched.Inputs[1].Streams[0].ID)
+
+	// When disabled input is enabled
+	expected = &FleetPackagePolicy{}
+	if err := json.Unmarshal([]byte(rawPolicy), expected); err != nil {
+		panic(err)
+	}
+	expected.Inputs = append(expected.Inputs, FleetPackagePolicyInput{
+		Type:           "system/metrics",
+		PolicyTemplate: "system",
+		Enabled:        true,
+		Streams: []FleetPackagePolicyInputStream{
+			{
+				Enabled: true,
+				DataStream: FleetPackagePolicyDataStream{
+					Type:    "metrics",
+					Dataset: "system.cpu",
+				},
+			},
+		},
+	})
+	diff, err = t.kbHandler.FleetPackagePolicyDiff(actual, expected, original)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.False(t.T(), diff.IsEmpty())
+	patched = diff.Patched.(*FleetPackagePolicy)
+	assert.Len(t.T(), patched.Inputs, 2)
+	assert.Equal(t.T(), "system/metrics", patched.Inputs[1].Type)
+	assert.True(t.T(), patched.Inputs[1].Enabled)
+	assert.Equal(t.T(), "system/metrics-system.cpu-test", patched.Inputs[1].Streams[0].ID)
+}
+
+func (t *KibanaHandlerTestSuite) TestEnsurePackageInstalled() {
+
+	url := fmt.Sprintf("%s/api/fleet/epm/packages/system/1.20.4", baseURL)
+
+	// When package already installed
+	httpmock.RegisterResponder("GET", url, httpmock.NewStringResponder(200, `{"item": {"name": "system", "version": "1.20.4", "status": "installed"}}`))
+	httpmock.RegisterResponder("POST", url, httpmock.NewStringResponder(200, `{"items": []}`))
+
+	err := t.kbHandler.EnsurePackageInstalled("system", "1.20.4")
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Equal(t.T(), 0, httpmock.GetCallCountInfo()["POST "+url])
+
+	// When package is not installed
+	httpmock.RegisterResponder("GET", url, httpmock.NewStringResponder(200, `{"item": {"name": "system", "version": "1.20.4", "status": "not_installed"}}`))
+	err = t.kbHandler.EnsurePackageInstalled("system", "1.20.4")
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Equal(t.T(), 1, httpmock.GetCallCountInfo()["POST "+url])
+
+	// When another version is installed
+	httpmock.RegisterResponder("GET", url, httpmock.NewStringResponder(200, `{"item": {"name": "system", "version": "1.20.4", "status": "installed", "savedObject": {"attributes": {"version": "1.10.0"}}}}`))
+	err = t.kbHandler.EnsurePackageInstalled("system", "1.20.4")
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Equal(t.T(), 2, httpmock.GetCallCountInfo()["POST "+url])
+
+	// When requested version is installed
+	httpmock.RegisterResponder("GET", url, httpmock.NewStringResponder(200, `{"item": {"name": "system", "version": "1.20.4", "status": "installed", "installationInfo": {"version": "1.20.4"}, "savedObject": {"attributes": {"version": "1.20.4"}}}}`))
+	err = t.kbHandler.EnsurePackageInstalled("system", "1.20.4")
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Equal(t.T(), 2, httpmock.GetCallCountInfo()["POST "+url])
+
+	// When error
+	httpmock.RegisterResponder("GET", url, httpmock.NewStringResponder(200, `{"item": {"name": "system", "version": "1.20.4", "status": "not_installed"}}`))
+	httpmock.RegisterResponder("POST", url, httpmock.NewErrorResponder(errors.New("fack error")))
+	err = t.kbHandler.EnsurePackageInstalled("system", "1.20.4")
+	assert.Error(t.T(), err)
+}
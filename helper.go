@@ -0,0 +1,58 @@
+package kbhandler
+
+import (
+	"fmt"
+	"net/http"
+
+	jsonIterator "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+)
+
+// userSpacePath permit to compute the API path on the user space
+// The default user space not need prefix
+func userSpacePath(userSpace, path string) string {
+	if userSpace == "" || userSpace == "default" {
+		return path
+	}
+
+	return fmt.Sprintf("/s/%s%s", userSpace, path)
+}
+
+// doRequest permit to call Kibana API not yet handled by go-kibana-rest
+// The body is sent as JSON and the response is decoded on result if provided
+// On dry run mode, only the read requests are sent
+func (h *KibanaHandlerImpl) doRequest(method, path string, body, result any) (err error) {
+	if h.isDryRun() && method != http.MethodGet {
+		return h.record(method, path, body)
+	}
+
+	req := h.newRequest()
+	if body != nil {
+		b, err := jsonIterator.ConfigCompatibleWithStandardLibrary.Marshal(body)
+		if err != nil {
+			return errors.Wrap(err, "Failed to convert body to byte sequence")
+		}
+		req.SetBody(b)
+	}
+
+	resp, err := req.Execute(method, path)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode() >= 300 {
+		return responseError(resp)
+	}
+
+	if result != nil && len(resp.Body()) > 0 {
+		if err = jsonIterator.ConfigCompatibleWithStandardLibrary.Unmarshal(resp.Body(), result); err != nil {
+			return errors.Wrap(err, "Failed to decode response")
+		}
+	}
+
+	return nil
+}
+
+// isNotFound return true if Kibana answer the object not exist
+func isNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
@@ -1,21 +1,30 @@
 package kbhandler
 
 import (
+	"context"
+	"net/http"
+	"net/url"
+
 	"github.com/disaster37/go-kibana-rest/v8"
 	"github.com/disaster37/go-kibana-rest/v8/kbapi"
 	"github.com/disaster37/generic-objectmatcher/patch"
+	"github.com/go-resty/resty/v2"
 	"github.com/sirupsen/logrus"
 )
 
 type KibanaHandler interface {
 	Client() (client *kibana.Client)
 	SetLogger(log *logrus.Entry)
+	Logger() (log Logger)
+	WithContext(ctx context.Context) KibanaHandler
+	RateLimitStats() (stats RateLimitStats)
 
 	// User space scope
 	UserSpaceCreate(kibanaSpace *kbapi.KibanaSpace) (err error)
 	UserSpaceUpdate(kibanaSpace *kbapi.KibanaSpace) (err error)
 	UserSpaceDelete(name string) (err error)
 	UserSpaceGet(name string) (userspace *kbapi.KibanaSpace, err error)
+	UserSpaceList(opts *ListOptions) (userspaces kbapi.KibanaSpaces, err error)
 	UserSpaceDiff(actualObject, expectedObject, originalObject *kbapi.KibanaSpace) (patchResult *patch.PatchResult, err error)
 	UserSpaceCopyObject(userSpaceOrigin string, copySpec *kbapi.KibanaSpaceCopySavedObjectParameter) (err error)
 
@@ -23,37 +32,211 @@ type KibanaHandler interface {
 	RoleUpdate(role *kbapi.KibanaRole) (err error)
 	RoleDelete(name string) (err error)
 	RoleGet(name string) (role *kbapi.KibanaRole, err error)
+	RoleList(opts *ListOptions) (roles kbapi.KibanaRoles, err error)
 	RoleDiff(actualObject, expectedObject, originalObject *kbapi.KibanaRole) (patchResult *patch.PatchResult, err error)
+	RoleValidate(role *kbapi.KibanaRole) (err error)
+	RoleCheckReferences(role *kbapi.KibanaRole) (missingSpaces []string, err error)
 
 	// Logstash pipeline scope
 	LogstashPipelineUpdate(pipeline *kbapi.LogstashPipeline) (err error)
 	LogstashPipelineDelete(name string) (err error)
 	LogstashPipelineGet(name string) (pipeline *kbapi.LogstashPipeline, err error)
+	LogstashPipelineList(opts *ListOptions) (pipelines kbapi.LogstashPipelines, err error)
 	LogstashPipelineDiff(actualObject, expectedObject, originalObject *kbapi.LogstashPipeline) (patchResult *patch.PatchResult, err error)
+	LogstashPipelineValidate(pipeline *kbapi.LogstashPipeline) (err error)
+
+	// Saved object scope
+	SavedObjectCreate(savedObject *SavedObject, userSpace string) (err error)
+	SavedObjectUpdate(savedObject *SavedObject, userSpace string) (err error)
+	SavedObjectDelete(objectType, id, userSpace string) (err error)
+	SavedObjectGet(objectType, id, userSpace string) (savedObject *SavedObject, err error)
+	SavedObjectDiff(actualObject, expectedObject, originalObject *SavedObject) (patchResult *patch.PatchResult, err error)
+
+	// Dashboard scope
+	DashboardExport(id, userSpace string) (data []byte, err error)
+	DashboardImport(data []byte, userSpace string, overwrite, createNewCopies bool) (err error)
+
+	// Data view scope
+	DataViewCreate(dataView *DataView, userSpace string) (err error)
+	DataViewUpdate(dataView *DataView, userSpace string) (err error)
+	DataViewDelete(id, userSpace string) (err error)
+	DataViewGet(id, userSpace string) (dataView *DataView, err error)
+	DataViewDiff(actualObject, expectedObject, originalObject *DataView) (patchResult *patch.PatchResult, err error)
+	SetDefaultDataView(id, userSpace string) (err error)
+
+	// Alerting rule scope
+	AlertingRuleCreate(rule *AlertingRule, userSpace string) (err error)
+	AlertingRuleUpdate(rule *AlertingRule, userSpace string) (err error)
+	AlertingRuleDelete(id, userSpace string) (err error)
+	AlertingRuleGet(id, userSpace string) (rule *AlertingRule, err error)
+	AlertingRuleDiff(actualObject, expectedObject, originalObject *AlertingRule) (patchResult *patch.PatchResult, err error)
+	AlertingRuleEnable(id, userSpace string) (err error)
+	AlertingRuleDisable(id, userSpace string) (err error)
+	AlertingRuleMuteAll(id, userSpace string) (err error)
+
+	// Connector scope
+	ConnectorCreate(connector *Connector, userSpace string) (err error)
+	ConnectorUpdate(connector *Connector, userSpace string) (err error)
+	ConnectorDelete(id, userSpace string) (err error)
+	ConnectorGet(id, userSpace string) (connector *Connector, err error)
+	ConnectorDiff(actualObject, expectedObject, originalObject *Connector) (patchResult *patch.PatchResult, err error)
+
+	// Fleet agent policy scope
+	FleetAgentPolicyCreate(policy *FleetAgentPolicy) (err error)
+	FleetAgentPolicyUpdate(policy *FleetAgentPolicy) (err error)
+	FleetAgentPolicyDelete(id string) (err error)
+	FleetAgentPolicyGet(id string) (policy *FleetAgentPolicy, err error)
+	FleetAgentPolicyDiff(actualObject, expectedObject, originalObject *FleetAgentPolicy) (patchResult *patch.PatchResult, err error)
+
+	// Fleet package policy scope
+	FleetPackagePolicyCreate(policy *FleetPackagePolicy) (err error)
+	FleetPackagePolicyUpdate(policy *FleetPackagePolicy) (err error)
+	FleetPackagePolicyDelete(id string) (err error)
+	FleetPackagePolicyGet(id string) (policy *FleetPackagePolicy, err error)
+	FleetPackagePolicyDiff(actualObject, expectedObject, originalObject *FleetPackagePolicy) (patchResult *patch.PatchResult, err error)
+	EnsurePackageInstalled(name, version string) (err error)
+
+	// Prune scope
+	Prune(opts *PruneOptions) (plan *PrunePlan, err error)
 }
 
 type KibanaHandlerImpl struct {
 	client *kibana.Client
-	log    *logrus.Entry
+	log    Logger
+
+	// ctx is the context of requests sent to Kibana, set by WithContext
+	ctx context.Context
+
+	// baseClient is the client without context, cloned by WithContext
+	baseClient *resty.Client
+
+	// ownership is stamped on objects written by the handler when set
+	ownership *Ownership
+
+	// forceOwnership permit to update or delete objects owned by another manager
+	forceOwnership bool
+
+	// plan record the mutating requests instead to send them when set
+	plan *Plan
+
+	// diffRules are the diff rules that replace the default diff rules
+	diffRules map[DiffKind]DiffRules
+
+	// logstashSemanticDiff permit to compare the Logstash pipeline configs by their AST
+	logstashSemanticDiff bool
+
+	// logstashValidation permit to validate the Logstash pipelines before create or update them
+	logstashValidation bool
+
+	// registry cache the features and privileges known by Kibana, used by RoleValidate
+	registry *privilegeRegistry
+
+	// roleIndexPrivilegeCheck is the behavior of RoleValidate on unknown index privileges
+	roleIndexPrivilegeCheck RoleIndexPrivilegeCheck
+
+	// roleSpaceCheck is the behavior of RoleUpdate when role reference user spaces that not exist
+	roleSpaceCheck RoleSpaceCheck
+
+	// retryPolicy is the retry policy of requests sent to Kibana
+	retryPolicy *RetryPolicy
+
+	// limiter is the rate limit of requests sent to Kibana
+	limiter *limiterTransport
+
+	// metrics are the Prometheus collectors where handler record its metrics
+	metrics *Metrics
+
+	// tracer create the OpenTelemetry spans of requests sent to Kibana
+	tracer *tracingTransport
 }
 
-func NewKibanaHandler(cfg kibana.Config, log *logrus.Entry) (KibanaHandler, error) {
+// Option permit to customize the handler
+type Option func(h *KibanaHandlerImpl)
+
+// NewKibanaHandler return the handler that log on the logrus entry
+// Use WithLogger option to log with logr, slog or any other logger. The logrus entry can be nil in this case
+func NewKibanaHandler(cfg kibana.Config, log *logrus.Entry, opts ...Option) (KibanaHandler, error) {
 
 	client, err := kibana.NewClient(cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	return &KibanaHandlerImpl{
-		client: client,
-		log:    log,
-	}, nil
+	handler := &KibanaHandlerImpl{
+		client:   client,
+		log:      NewLogrusLogger(log),
+		registry: &privilegeRegistry{},
+	}
+	for _, opt := range opts {
+		opt(handler)
+	}
+	handler.setupTransport()
+
+	return handler, nil
 }
 
+// SetLogger permit to replace the logger by logrus entry
+// It's kept for backward compatibility, WithLogger option support any logger
 func (h *KibanaHandlerImpl) SetLogger(log *logrus.Entry) {
-	h.log = log
+	h.log = NewLogrusLogger(log)
+}
+
+// Logger return the logger used by handler
+func (h *KibanaHandlerImpl) Logger() Logger {
+	return h.log
 }
 
 func (h *KibanaHandlerImpl) Client() *kibana.Client {
 	return h.client
 }
+
+// WithContext return a copy of the handler where all requests sent to Kibana use the provided context
+// It permit to cancel in-flight calls or to enforce deadlines
+func (h *KibanaHandlerImpl) WithContext(ctx context.Context) KibanaHandler {
+	baseClient := h.baseClient
+	if baseClient == nil {
+		baseClient = h.client.Client
+	}
+	contextClient := newContextClient(baseClient, ctx)
+
+	handler := *h
+	handler.ctx = ctx
+	handler.baseClient = baseClient
+	handler.client = &kibana.Client{
+		Client: contextClient,
+		API:    kbapi.New(contextClient),
+	}
+
+	return &handler
+}
+
+// newRequest return request on Kibana that use the context of handler
+func (h *KibanaHandlerImpl) newRequest() *resty.Request {
+	req := h.client.Client.R()
+	if h.ctx != nil {
+		req.SetContext(h.ctx)
+	}
+
+	return req
+}
+
+// newContextClient return a clone of client, with all its settings, where all requests use the provided context
+// It's needed by go-kibana-rest API that not permit to set the context per request
+// The HTTP client is shared, so the clone use the same transport (rate limit, retry, metrics and tracing)
+func newContextClient(client *resty.Client, ctx context.Context) *resty.Client {
+	contextClient := *client
+	contextClient.Header = client.Header.Clone()
+	contextClient.QueryParam = url.Values(http.Header(client.QueryParam).Clone())
+	contextClient.FormData = url.Values(http.Header(client.FormData).Clone())
+	contextClient.PathParams = make(map[string]string, len(client.PathParams))
+	for key, value := range client.PathParams {
+		contextClient.PathParams[key] = value
+	}
+	contextClient.Cookies = append([]*http.Cookie{}, client.Cookies...)
+	contextClient.SetPreRequestHook(func(_ *resty.Client, req *http.Request) error {
+		*req = *req.WithContext(ctx)
+		return nil
+	})
+
+	return &contextClient
+}
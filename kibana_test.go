@@ -0,0 +1,85 @@
+package kbhandler
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func (t *KibanaHandlerTestSuite) TestWithContext() {
+
+	httpmock.RegisterResponder("GET", urlrole, func(req *http.Request) (*http.Response, error) {
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(100 * time.Millisecond):
+			return httpmock.NewStringResponse(200, `{"name": "test"}`), nil
+		}
+	})
+
+	// When context is alive
+	role, err := t.kbHandler.WithContext(context.Background()).RoleGet("test")
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Equal(t.T(), "test", role.Name)
+
+	// When context is canceled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = t.kbHandler.WithContext(ctx).RoleGet("test")
+	assert.ErrorIs(t.T(), err, context.Canceled)
+
+	// When deadline is exceeded
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = t.kbHandler.WithContext(ctx).RoleGet("test")
+	assert.ErrorIs(t.T(), err, context.DeadlineExceeded)
+	_, err = t.kbHandler.WithContext(context.Background()).WithContext(ctx).RoleGet("test")
+	assert.ErrorIs(t.T(), err, context.DeadlineExceeded)
+
+	// The handler without context is not impacted
+	_, err = t.kbHandler.RoleGet("test")
+	assert.NoError(t.T(), err)
+
+	// The requests not handled by go-kibana-rest use the context too
+	httpmock.RegisterResponder("GET", urlAlertingRule, func(req *http.Request) (*http.Response, error) {
+		if err := req.Context().Err(); err != nil {
+			return nil, err
+		}
+		return httpmock.NewStringResponse(200, `{"id": "test", "name": "test"}`), nil
+	})
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	_, err = t.kbHandler.WithContext(ctx).AlertingRuleGet("test", "test")
+	assert.ErrorIs(t.T(), err, context.Canceled)
+	_, err = t.kbHandler.AlertingRuleGet("test", "test")
+	assert.NoError(t.T(), err)
+
+	// The client of handler created with context keep the settings and use the context
+	t.kbHandler.Client().Client.SetHeader("X-Test", "test")
+	httpmock.RegisterResponder("GET", urlrole, func(req *http.Request) (*http.Response, error) {
+		if err := req.Context().Err(); err != nil {
+			return nil, err
+		}
+		if req.Header.Get("X-Test") != "test" {
+			return httpmock.NewStringResponse(400, `{}`), nil
+		}
+		return httpmock.NewStringResponse(200, `{"name": "test"}`), nil
+	})
+	_, err = t.kbHandler.WithContext(ctx).Client().Client.R().Get("/api/security/role/test")
+	assert.ErrorIs(t.T(), err, context.Canceled)
+	resp, err := t.kbHandler.WithContext(context.Background()).Client().Client.R().Get("/api/security/role/test")
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Equal(t.T(), 200, resp.StatusCode())
+	resp, err = t.kbHandler.Client().Client.R().Get("/api/security/role/test")
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Equal(t.T(), 200, resp.StatusCode())
+}
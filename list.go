@@ -0,0 +1,54 @@
+package kbhandler
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ListOptions permit to filter the objects returned by list methods
+type ListOptions struct {
+	// NamePrefix keep only objects where name (or ID) start with this prefix
+	NamePrefix string
+
+	// Reserved keep only reserved objects when true, or non reserved objects when false
+	// It apply on roles and user spaces
+	Reserved *bool
+
+	// Labels keep only objects where metadata contain all these key / value
+	// It apply on roles
+	Labels map[string]string
+}
+
+// matchName return true if name match the name prefix
+func (o *ListOptions) matchName(name string) bool {
+	if o == nil {
+		return true
+	}
+
+	return strings.HasPrefix(name, o.NamePrefix)
+}
+
+// matchReserved return true if reserved flag match the reserved filter
+func (o *ListOptions) matchReserved(reserved bool) bool {
+	if o == nil || o.Reserved == nil {
+		return true
+	}
+
+	return *o.Reserved == reserved
+}
+
+// matchLabels return true if metadata contain all labels
+func (o *ListOptions) matchLabels(metadata map[string]interface{}) bool {
+	if o == nil {
+		return true
+	}
+
+	for key, value := range o.Labels {
+		v, ok := metadata[key]
+		if !ok || fmt.Sprint(v) != value {
+			return false
+		}
+	}
+
+	return true
+}
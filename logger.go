@@ -0,0 +1,115 @@
+package kbhandler
+
+import (
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"github.com/sirupsen/logrus"
+)
+
+// Logger is the logger used by handler
+// The keysAndValues are the structured fields of message, like "scope", "role", "name", "admin"
+type Logger interface {
+	Debug(msg string, keysAndValues ...any)
+	Info(msg string, keysAndValues ...any)
+	Warn(msg string, keysAndValues ...any)
+	Error(err error, msg string, keysAndValues ...any)
+}
+
+// WithLogger permit to use any logger, like the logr or slog adapters
+// It override the logrus logger provided to NewKibanaHandler
+func WithLogger(log Logger) Option {
+	return func(h *KibanaHandlerImpl) {
+		h.log = log
+	}
+}
+
+// logrusLogger is the logrus adapter of Logger
+type logrusLogger struct {
+	log *logrus.Entry
+}
+
+// NewLogrusLogger return the Logger that write on logrus entry
+// The key/value pairs are added as logrus fields
+func NewLogrusLogger(log *logrus.Entry) Logger {
+	if log == nil {
+		return NewNopLogger()
+	}
+
+	return &logrusLogger{log: log}
+}
+
+func (l *logrusLogger) Debug(msg string, keysAndValues ...any) {
+	l.log.WithFields(toFields(keysAndValues)).Debug(msg)
+}
+
+func (l *logrusLogger) Info(msg string, keysAndValues ...any) {
+	l.log.WithFields(toFields(keysAndValues)).Info(msg)
+}
+
+func (l *logrusLogger) Warn(msg string, keysAndValues ...any) {
+	l.log.WithFields(toFields(keysAndValues)).Warn(msg)
+}
+
+func (l *logrusLogger) Error(err error, msg string, keysAndValues ...any) {
+	l.log.WithFields(toFields(keysAndValues)).WithError(err).Error(msg)
+}
+
+// logrLogger is the logr adapter of Logger
+type logrLogger struct {
+	log logr.Logger
+}
+
+// NewLogrLogger return the Logger that write on logr logger
+// Debug messages are written with verbosity 1, and warnings are written as info with the field level=warn because logr not have warning level
+func NewLogrLogger(log logr.Logger) Logger {
+	return &logrLogger{log: log}
+}
+
+func (l *logrLogger) Debug(msg string, keysAndValues ...any) {
+	l.log.V(1).Info(msg, keysAndValues...)
+}
+
+func (l *logrLogger) Info(msg string, keysAndValues ...any) {
+	l.log.Info(msg, keysAndValues...)
+}
+
+func (l *logrLogger) Warn(msg string, keysAndValues ...any) {
+	l.log.Info(msg, append([]any{"level", "warn"}, keysAndValues...)...)
+}
+
+func (l *logrLogger) Error(err error, msg string, keysAndValues ...any) {
+	l.log.Error(err, msg, keysAndValues...)
+}
+
+// nopLogger is the Logger that discard all messages
+type nopLogger struct{}
+
+// NewNopLogger return the Logger that discard all messages
+func NewNopLogger() Logger {
+	return nopLogger{}
+}
+
+func (nopLogger) Debug(msg string, keysAndValues ...any)            {}
+func (nopLogger) Info(msg string, keysAndValues ...any)             {}
+func (nopLogger) Warn(msg string, keysAndValues ...any)             {}
+func (nopLogger) Error(err error, msg string, keysAndValues ...any) {}
+
+// toFields convert the key/value pairs to logrus fields
+// A key without value get the value "(MISSING)", like slog do
+func toFields(keysAndValues []any) logrus.Fields {
+	fields := make(logrus.Fields, len(keysAndValues)/2)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key, ok := keysAndValues[i].(string)
+		if !ok {
+			key = fmt.Sprint(keysAndValues[i])
+		}
+		if i+1 < len(keysAndValues) {
+			fields[key] = keysAndValues[i+1]
+		} else {
+			fields[key] = "(MISSING)"
+		}
+	}
+
+	return fields
+}
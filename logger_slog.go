@@ -0,0 +1,38 @@
+//go:build go1.21
+
+package kbhandler
+
+import (
+	"log/slog"
+)
+
+// slogLogger is the slog adapter of Logger
+type slogLogger struct {
+	log *slog.Logger
+}
+
+// NewSlogLogger return the Logger that write on slog logger
+// It's only available when build with Go 1.21 or later
+func NewSlogLogger(log *slog.Logger) Logger {
+	if log == nil {
+		log = slog.Default()
+	}
+
+	return &slogLogger{log: log}
+}
+
+func (l *slogLogger) Debug(msg string, keysAndValues ...any) {
+	l.log.Debug(msg, keysAndValues...)
+}
+
+func (l *slogLogger) Info(msg string, keysAndValues ...any) {
+	l.log.Info(msg, keysAndValues...)
+}
+
+func (l *slogLogger) Warn(msg string, keysAndValues ...any) {
+	l.log.Warn(msg, keysAndValues...)
+}
+
+func (l *slogLogger) Error(err error, msg string, keysAndValues ...any) {
+	l.log.Error(msg, append([]any{"error", err}, keysAndValues...)...)
+}
@@ -0,0 +1,34 @@
+//go:build go1.21
+
+package kbhandler
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlogLogger(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logger := NewSlogLogger(slog.New(slog.NewTextHandler(buffer, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})))
+
+	logger.Debug("Get role", "scope", "role", "name", "test")
+	logger.Warn("Role reference user spaces that not exist", "spaces", "test")
+	logger.Error(errors.New("fake error"), "Failed", "scope", "role")
+
+	assert.Equal(t, `level=DEBUG msg="Get role" scope=role name=test
+level=WARN msg="Role reference user spaces that not exist" spaces=test
+level=ERROR msg=Failed error="fake error" scope=role
+`, buffer.String())
+}
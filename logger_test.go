@@ -0,0 +1,99 @@
+package kbhandler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr/funcr"
+	"github.com/jarcoal/httpmock"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogrusLogger(t *testing.T) {
+	log, hook := test.NewNullLogger()
+	log.SetLevel(logrus.DebugLevel)
+	logger := NewLogrusLogger(logrus.NewEntry(log))
+
+	logger.Debug("Get role", "scope", "role", "name", "test")
+	logger.Warn("Missing value", "name")
+	logger.Error(errors.New("fake error"), "Failed", "scope", "role")
+
+	if !assert.Len(t, hook.AllEntries(), 3) {
+		return
+	}
+	assert.Equal(t, logrus.DebugLevel, hook.AllEntries()[0].Level)
+	assert.Equal(t, "Get role", hook.AllEntries()[0].Message)
+	assert.Equal(t, logrus.Fields{"scope": "role", "name": "test"}, hook.AllEntries()[0].Data)
+	assert.Equal(t, logrus.WarnLevel, hook.AllEntries()[1].Level)
+	assert.Equal(t, logrus.Fields{"name": "(MISSING)"}, hook.AllEntries()[1].Data)
+	assert.Equal(t, logrus.ErrorLevel, hook.AllEntries()[2].Level)
+	assert.EqualError(t, hook.AllEntries()[2].Data[logrus.ErrorKey].(error), "fake error")
+
+	// Nil entry discard messages
+	assert.Equal(t, NewNopLogger(), NewLogrusLogger(nil))
+}
+
+func TestLogrLogger(t *testing.T) {
+	lines := []string{}
+	logger := NewLogrLogger(funcr.New(func(prefix, args string) {
+		lines = append(lines, args)
+	}, funcr.Options{Verbosity: 1}))
+
+	logger.Debug("Get role", "scope", "role", "name", "test")
+	logger.Info("Prune owned objects")
+	logger.Warn("Role reference user spaces that not exist", "spaces", "test")
+	logger.Error(errors.New("fake error"), "Failed")
+
+	assert.Equal(t, []string{
+		`"level"=1 "msg"="Get role" "scope"="role" "name"="test"`,
+		`"level"=0 "msg"="Prune owned objects"`,
+		`"level"=0 "msg"="Role reference user spaces that not exist" "level"="warn" "spaces"="test"`,
+		`"msg"="Failed" "error"="fake error"`,
+	}, lines)
+}
+
+func (t *KibanaHandlerTestSuite) TestSetLogger() {
+	log, hook := test.NewNullLogger()
+	log.SetLevel(logrus.DebugLevel)
+
+	handler := *t.kbHandler.(*KibanaHandlerImpl)
+	WithRetryPolicy(RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond, RetryableStatusCodes: []int{http.StatusServiceUnavailable}})(&handler)
+	handler.setupTransport()
+	handler.SetLogger(logrus.NewEntry(log))
+
+	httpmock.RegisterResponder("GET", fmt.Sprintf("%s/s/test/api/actions/connector/test", baseURL), httpmock.NewStringResponder(http.StatusNotFound, `{}`))
+	_, err := handler.ConnectorGet("test", "test")
+	if err != nil {
+		t.Fail(err.Error())
+	}
+
+	if assert.NotNil(t.T(), hook.LastEntry()) {
+		assert.Equal(t.T(), "Get connector", hook.LastEntry().Message)
+		assert.Equal(t.T(), logrus.Fields{"scope": "connector", "name": "test", "space": "test"}, hook.LastEntry().Data)
+	}
+	assert.Equal(t.T(), &logrusLogger{log: logrus.NewEntry(log)}, handler.Logger())
+
+	// When retry, the logger set after the transport is used
+	calls := 0
+	httpmock.RegisterResponder("GET", urlrole, func(req *http.Request) (*http.Response, error) {
+		calls++
+		if calls == 1 {
+			return httpmock.NewStringResponse(http.StatusServiceUnavailable, ""), nil
+		}
+		return httpmock.NewStringResponse(http.StatusOK, `{"name": "test"}`), nil
+	})
+	_, err = handler.RoleGet("test")
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	messages := []string{}
+	for _, entry := range hook.AllEntries() {
+		messages = append(messages, entry.Message)
+	}
+	assert.Contains(t.T(), messages, "Retry request after status code")
+}
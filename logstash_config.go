@@ -0,0 +1,788 @@
+package kbhandler
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// LogstashConfig is the AST of Logstash pipeline config
+// The comments and the layout are not kept, so two configs with the same String() have the same behavior
+type LogstashConfig struct {
+	Sections []*LogstashSection
+}
+
+// LogstashSection is the input, filter or output section
+type LogstashSection struct {
+	Type       string
+	Statements []LogstashStatement
+	Line       int
+	Column     int
+}
+
+// LogstashStatement is a plugin or a branch on section
+type LogstashStatement interface {
+	write(sb *strings.Builder, indent int)
+}
+
+// LogstashValue is the value of plugin attribute
+// It can be LogstashString, LogstashNumber, LogstashArray, LogstashHash or LogstashPlugin (like codec)
+type LogstashValue interface {
+	write(sb *strings.Builder, indent int)
+}
+
+// LogstashPlugin is a plugin with its attributes
+type LogstashPlugin struct {
+	Name       string
+	Attributes []*LogstashAttribute
+	Line       int
+	Column     int
+}
+
+// LogstashAttribute is a plugin setting, like hosts => ["localhost"]
+type LogstashAttribute struct {
+	Name   string
+	Value  LogstashValue
+	Line   int
+	Column int
+}
+
+// LogstashBranch is the conditional if / else if / else
+type LogstashBranch struct {
+	Conditions []*LogstashCondition
+}
+
+// LogstashCondition is one block of branch. The expression is empty for else block
+type LogstashCondition struct {
+	Expression string
+	Statements []LogstashStatement
+	Line       int
+	Column     int
+}
+
+// LogstashString is a quoted string or a bareword
+type LogstashString string
+
+// LogstashNumber is a number, as written on config
+type LogstashNumber string
+
+// LogstashArray is a list of values
+type LogstashArray []LogstashValue
+
+// LogstashHash is a list of key / value
+type LogstashHash []*LogstashHashEntry
+
+// LogstashHashEntry is one key / value of hash
+type LogstashHashEntry struct {
+	Key   string
+	Value LogstashValue
+}
+
+// LogstashConfigError is a syntax error on Logstash pipeline config
+type LogstashConfigError struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *LogstashConfigError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
+
+// ParseLogstashConfig permit to parse Logstash pipeline config
+// It return LogstashConfigError when config is invalid
+func ParseLogstashConfig(config string) (logstashConfig *LogstashConfig, err error) {
+	p := &logstashParser{
+		input:  []rune(config),
+		line:   1,
+		column: 1,
+	}
+
+	return p.parseConfig()
+}
+
+// LogstashConfigEqual return true if the two pipeline configs are the same, without take care of comments and layout
+// It return false if one of them is invalid and they are not strictly equal
+func LogstashConfigEqual(config1, config2 string) bool {
+	if config1 == config2 {
+		return true
+	}
+
+	c1, err := ParseLogstashConfig(config1)
+	if err != nil {
+		return false
+	}
+	c2, err := ParseLogstashConfig(config2)
+	if err != nil {
+		return false
+	}
+
+	return c1.String() == c2.String()
+}
+
+// String return the canonical form of config
+func (c *LogstashConfig) String() string {
+	sb := &strings.Builder{}
+	for i, section := range c.Sections {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString(section.Type)
+		sb.WriteString(" {\n")
+		writeStatements(sb, section.Statements, 1)
+		sb.WriteString("}\n")
+	}
+
+	return sb.String()
+}
+
+func writeStatements(sb *strings.Builder, statements []LogstashStatement, indent int) {
+	for _, statement := range statements {
+		writeIndent(sb, indent)
+		statement.write(sb, indent)
+		sb.WriteString("\n")
+	}
+}
+
+func writeIndent(sb *strings.Builder, indent int) {
+	sb.WriteString(strings.Repeat("  ", indent))
+}
+
+func (p *LogstashPlugin) write(sb *strings.Builder, indent int) {
+	sb.WriteString(formatLogstashName(p.Name))
+	if len(p.Attributes) == 0 {
+		sb.WriteString(" {}")
+		return
+	}
+	sb.WriteString(" {\n")
+	for _, attribute := range p.Attributes {
+		writeIndent(sb, indent+1)
+		sb.WriteString(formatLogstashName(attribute.Name))
+		sb.WriteString(" => ")
+		attribute.Value.write(sb, indent+1)
+		sb.WriteString("\n")
+	}
+	writeIndent(sb, indent)
+	sb.WriteString("}")
+}
+
+func (b *LogstashBranch) write(sb *strings.Builder, indent int) {
+	for i, condition := range b.Conditions {
+		switch {
+		case i == 0:
+			sb.WriteString("if ")
+			sb.WriteString(condition.Expression)
+		case condition.Expression != "":
+			sb.WriteString(" else if ")
+			sb.WriteString(condition.Expression)
+		default:
+			sb.WriteString(" else")
+		}
+		sb.WriteString(" {\n")
+		writeStatements(sb, condition.Statements, indent+1)
+		writeIndent(sb, indent)
+		sb.WriteString("}")
+	}
+}
+
+func (s LogstashString) write(sb *strings.Builder, _ int) {
+	sb.WriteString(quoteLogstashString(string(s)))
+}
+
+func (n LogstashNumber) write(sb *strings.Builder, _ int) {
+	sb.WriteString(string(n))
+}
+
+func (a LogstashArray) write(sb *strings.Builder, indent int) {
+	sb.WriteString("[")
+	for i, value := range a {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		value.write(sb, indent)
+	}
+	sb.WriteString("]")
+}
+
+func (h LogstashHash) write(sb *strings.Builder, indent int) {
+	if len(h) == 0 {
+		sb.WriteString("{}")
+		return
+	}
+	sb.WriteString("{")
+	for _, entry := range h {
+		sb.WriteString(" ")
+		sb.WriteString(quoteLogstashString(entry.Key))
+		sb.WriteString(" => ")
+		entry.Value.write(sb, indent)
+	}
+	sb.WriteString(" }")
+}
+
+// formatLogstashName return the name as bareword if possible, else as quoted string
+func formatLogstashName(name string) string {
+	if name == "" {
+		return quoteLogstashString(name)
+	}
+	for i, r := range name {
+		if !isBarewordRune(r, i == 0) {
+			return quoteLogstashString(name)
+		}
+	}
+
+	return name
+}
+
+// quoteLogstashString quote the string content with double quote, or simple quote if it contain unescaped double quote
+func quoteLogstashString(value string) string {
+	quote := `"`
+	escaped := false
+	for _, r := range value {
+		if r == '"' && !escaped {
+			quote = `'`
+			break
+		}
+		escaped = r == '\\' && !escaped
+	}
+
+	return quote + value + quote
+}
+
+func isBarewordRune(r rune, first bool) bool {
+	if r == '_' || (r < unicode.MaxASCII && unicode.IsLetter(r)) {
+		return true
+	}
+
+	return !first && r < unicode.MaxASCII && unicode.IsDigit(r)
+}
+
+// logstashParser is a recursive descent parser of Logstash config language
+type logstashParser struct {
+	input  []rune
+	pos    int
+	line   int
+	column int
+
+	// spans are the positions of setting and hash values, used to mask secrets
+	spans []logstashValueSpan
+}
+
+// logstashValueSpan is the position of value of setting or hash key on config
+type logstashValueSpan struct {
+	name  string
+	start int
+	end   int
+}
+
+// logstashValueSpans return the positions of setting and hash values of config, as rune offsets
+func logstashValueSpans(config string) (spans []logstashValueSpan, err error) {
+	p := &logstashParser{
+		input:  []rune(config),
+		line:   1,
+		column: 1,
+	}
+	if _, err = p.parseConfig(); err != nil {
+		return nil, err
+	}
+
+	return p.spans, nil
+}
+
+func (p *logstashParser) errorf(format string, args ...any) error {
+	return &LogstashConfigError{
+		Line:    p.line,
+		Column:  p.column,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+func (p *logstashParser) eof() bool {
+	return p.pos >= len(p.input)
+}
+
+func (p *logstashParser) peek() rune {
+	if p.eof() {
+		return 0
+	}
+
+	return p.input[p.pos]
+}
+
+func (p *logstashParser) peekAt(offset int) rune {
+	if p.pos+offset >= len(p.input) {
+		return 0
+	}
+
+	return p.input[p.pos+offset]
+}
+
+func (p *logstashParser) next() rune {
+	r := p.input[p.pos]
+	p.pos++
+	if r == '\n' {
+		p.line++
+		p.column = 1
+	} else {
+		p.column++
+	}
+
+	return r
+}
+
+// skipSpaces skip whitespaces and comments
+func (p *logstashParser) skipSpaces() {
+	for !p.eof() {
+		r := p.peek()
+		switch {
+		case unicode.IsSpace(r):
+			p.next()
+		case r == '#':
+			for !p.eof() && p.peek() != '\n' {
+				p.next()
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (p *logstashParser) describe() string {
+	if p.eof() {
+		return "end of config"
+	}
+
+	return fmt.Sprintf("%q", p.peek())
+}
+
+func (p *logstashParser) expect(r rune) error {
+	p.skipSpaces()
+	if p.peek() != r {
+		return p.errorf("Expected %q, got %s", r, p.describe())
+	}
+	p.next()
+
+	return nil
+}
+
+// peekWord return the bareword at current position without consume it
+func (p *logstashParser) peekWord() string {
+	end := p.pos
+	for end < len(p.input) && isBarewordRune(p.input[end], end == p.pos) {
+		end++
+	}
+
+	return string(p.input[p.pos:end])
+}
+
+func (p *logstashParser) parseBareword() (string, error) {
+	word := p.peekWord()
+	if word == "" {
+		return "", p.errorf("Expected bareword, got %s", p.describe())
+	}
+	for range word {
+		p.next()
+	}
+
+	return word, nil
+}
+
+func (p *logstashParser) parseString() (string, error) {
+	quote := p.next()
+	sb := &strings.Builder{}
+	for {
+		if p.eof() {
+			return "", p.errorf("Unterminated string")
+		}
+		r := p.next()
+		if r == quote {
+			return sb.String(), nil
+		}
+		sb.WriteRune(r)
+		if r == '\\' && !p.eof() {
+			sb.WriteRune(p.next())
+		}
+	}
+}
+
+func (p *logstashParser) parseName() (string, error) {
+	if r := p.peek(); r == '"' || r == '\'' {
+		return p.parseString()
+	}
+
+	return p.parseBareword()
+}
+
+func (p *logstashParser) parseNumber() (LogstashNumber, error) {
+	start := p.pos
+	if p.peek() == '-' {
+		p.next()
+	}
+	if !unicode.IsDigit(p.peek()) {
+		return "", p.errorf("Expected number, got %s", p.describe())
+	}
+	for unicode.IsDigit(p.peek()) {
+		p.next()
+	}
+	if p.peek() == '.' {
+		p.next()
+		for unicode.IsDigit(p.peek()) {
+			p.next()
+		}
+	}
+
+	return LogstashNumber(p.input[start:p.pos]), nil
+}
+
+func (p *logstashParser) parseConfig() (*LogstashConfig, error) {
+	config := &LogstashConfig{}
+	for {
+		p.skipSpaces()
+		if p.eof() {
+			return config, nil
+		}
+
+		section := &LogstashSection{Line: p.line, Column: p.column}
+		sectionType := p.peekWord()
+		switch sectionType {
+		case "input", "filter", "output":
+		default:
+			return nil, p.errorf("Expected input, filter or output, got %s", p.describe())
+		}
+		section.Type, _ = p.parseBareword()
+
+		if err := p.expect('{'); err != nil {
+			return nil, err
+		}
+		statements, err := p.parseStatements()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect('}'); err != nil {
+			return nil, err
+		}
+		section.Statements = statements
+		config.Sections = append(config.Sections, section)
+	}
+}
+
+// parseStatements parse plugins and branches until the end of block
+func (p *logstashParser) parseStatements() ([]LogstashStatement, error) {
+	statements := []LogstashStatement{}
+	for {
+		p.skipSpaces()
+		if p.eof() || p.peek() == '}' {
+			return statements, nil
+		}
+
+		var statement LogstashStatement
+		var err error
+		if p.peekWord() == "if" {
+			statement, err = p.parseBranch()
+		} else {
+			statement, err = p.parsePlugin()
+		}
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, statement)
+	}
+}
+
+func (p *logstashParser) parsePlugin() (*LogstashPlugin, error) {
+	line, column := p.line, p.column
+	name, err := p.parseName()
+	if err != nil {
+		return nil, p.errorf("Expected plugin name, got %s", p.describe())
+	}
+
+	return p.parsePluginBody(name, line, column)
+}
+
+func (p *logstashParser) parsePluginBody(name string, line, column int) (*LogstashPlugin, error) {
+	plugin := &LogstashPlugin{
+		Name:       name,
+		Attributes: []*LogstashAttribute{},
+		Line:       line,
+		Column:     column,
+	}
+	if err := p.expect('{'); err != nil {
+		return nil, err
+	}
+	for {
+		p.skipSpaces()
+		if p.eof() {
+			return nil, p.errorf("Expected '}' to close plugin %s, got end of config", name)
+		}
+		if p.peek() == '}' {
+			p.next()
+			return plugin, nil
+		}
+
+		attribute, err := p.parseAttribute()
+		if err != nil {
+			return nil, err
+		}
+		plugin.Attributes = append(plugin.Attributes, attribute)
+	}
+}
+
+func (p *logstashParser) parseAttribute() (*LogstashAttribute, error) {
+	attribute := &LogstashAttribute{Line: p.line, Column: p.column}
+	name, err := p.parseName()
+	if err != nil {
+		return nil, p.errorf("Expected setting name, got %s", p.describe())
+	}
+	attribute.Name = name
+
+	if err = p.expectArrow(); err != nil {
+		return nil, err
+	}
+	p.skipSpaces()
+	start := p.pos
+	if attribute.Value, err = p.parseValue(); err != nil {
+		return nil, err
+	}
+	p.spans = append(p.spans, logstashValueSpan{name: name, start: start, end: p.pos})
+
+	return attribute, nil
+}
+
+func (p *logstashParser) expectArrow() error {
+	p.skipSpaces()
+	if p.peek() != '=' || p.peekAt(1) != '>' {
+		return p.errorf("Expected '=>', got %s", p.describe())
+	}
+	p.next()
+	p.next()
+
+	return nil
+}
+
+func (p *logstashParser) parseValue() (LogstashValue, error) {
+	p.skipSpaces()
+	r := p.peek()
+	switch {
+	case r == '"' || r == '\'':
+		s, err := p.parseString()
+		return LogstashString(s), err
+	case r == '[':
+		return p.parseArray()
+	case r == '{':
+		return p.parseHash()
+	case r == '-' || unicode.IsDigit(r):
+		return p.parseNumber()
+	case isBarewordRune(r, true):
+		line, column := p.line, p.column
+		word, _ := p.parseBareword()
+		p.skipSpaces()
+		if p.peek() == '{' {
+			return p.parsePluginBody(word, line, column)
+		}
+		return LogstashString(word), nil
+	default:
+		return nil, p.errorf("Expected value, got %s", p.describe())
+	}
+}
+
+func (p *logstashParser) parseArray() (LogstashArray, error) {
+	p.next()
+	array := LogstashArray{}
+	p.skipSpaces()
+	if p.peek() == ']' {
+		p.next()
+		return array, nil
+	}
+	for {
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		array = append(array, value)
+
+		p.skipSpaces()
+		switch p.peek() {
+		case ',':
+			p.next()
+		case ']':
+			p.next()
+			return array, nil
+		default:
+			return nil, p.errorf("Expected ',' or ']', got %s", p.describe())
+		}
+	}
+}
+
+func (p *logstashParser) parseHash() (LogstashHash, error) {
+	p.next()
+	hash := LogstashHash{}
+	for {
+		p.skipSpaces()
+		if p.eof() {
+			return nil, p.errorf("Expected '}' to close hash, got end of config")
+		}
+		if p.peek() == '}' {
+			p.next()
+			return hash, nil
+		}
+
+		var key string
+		var err error
+		if r := p.peek(); r == '-' || unicode.IsDigit(r) {
+			var number LogstashNumber
+			number, err = p.parseNumber()
+			key = string(number)
+		} else {
+			key, err = p.parseName()
+		}
+		if err != nil {
+			return nil, p.errorf("Expected hash key, got %s", p.describe())
+		}
+		if err = p.expectArrow(); err != nil {
+			return nil, err
+		}
+		p.skipSpaces()
+		start := p.pos
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		p.spans = append(p.spans, logstashValueSpan{name: key, start: start, end: p.pos})
+		hash = append(hash, &LogstashHashEntry{Key: key, Value: value})
+	}
+}
+
+func (p *logstashParser) parseBranch() (*LogstashBranch, error) {
+	branch := &LogstashBranch{}
+	for {
+		condition := &LogstashCondition{Line: p.line, Column: p.column}
+		word, _ := p.parseBareword()
+
+		// else if
+		if word == "else" {
+			p.skipSpaces()
+			if p.peekWord() == "if" {
+				word, _ = p.parseBareword()
+			}
+		}
+
+		if word == "if" {
+			expression, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			condition.Expression = expression
+		}
+
+		if err := p.expect('{'); err != nil {
+			return nil, err
+		}
+		statements, err := p.parseStatements()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect('}'); err != nil {
+			return nil, err
+		}
+		condition.Statements = statements
+		branch.Conditions = append(branch.Conditions, condition)
+
+		// Only else can follow else
+		p.skipSpaces()
+		if condition.Expression == "" || p.peekWord() != "else" {
+			return branch, nil
+		}
+	}
+}
+
+// parseExpression read the condition until the block, and return it with canonical spacing
+func (p *logstashParser) parseExpression() (string, error) {
+	tokens := []string{}
+	depth := 0
+	for {
+		p.skipSpaces()
+		if p.eof() {
+			return "", p.errorf("Expected '{' after condition, got end of config")
+		}
+
+		r := p.peek()
+		switch {
+		case r == '{':
+			if depth != 0 {
+				return "", p.errorf("Unbalanced brackets on condition")
+			}
+			if len(tokens) == 0 {
+				return "", p.errorf("Expected condition, got %s", p.describe())
+			}
+			return joinExpressionTokens(tokens), nil
+		case r == '"' || r == '\'':
+			s, err := p.parseString()
+			if err != nil {
+				return "", err
+			}
+			tokens = append(tokens, quoteLogstashString(s))
+		case r == '/' && len(tokens) > 0 && (tokens[len(tokens)-1] == "=~" || tokens[len(tokens)-1] == "!~"):
+			start := p.pos
+			p.next()
+			for !p.eof() && p.peek() != '/' {
+				if p.next() == '\\' && !p.eof() {
+					p.next()
+				}
+			}
+			if p.eof() {
+				return "", p.errorf("Unterminated regexp")
+			}
+			p.next()
+			tokens = append(tokens, string(p.input[start:p.pos]))
+		case r == '[' || r == '(':
+			depth++
+			tokens = append(tokens, string(p.next()))
+		case r == ']' || r == ')':
+			depth--
+			if depth < 0 {
+				return "", p.errorf("Unexpected %q on condition", r)
+			}
+			tokens = append(tokens, string(p.next()))
+		case r == ',':
+			tokens = append(tokens, string(p.next()))
+		case strings.ContainsRune("=!<>", r):
+			op := string(p.next())
+			if next := p.peek(); next == '=' || next == '~' {
+				op += string(p.next())
+			}
+			switch op {
+			case "==", "!=", "<=", ">=", "=~", "!~", "<", ">", "!":
+			default:
+				return "", p.errorf("Unexpected operator %q on condition", op)
+			}
+			tokens = append(tokens, op)
+		default:
+			start := p.pos
+			for !p.eof() && !unicode.IsSpace(p.peek()) && !strings.ContainsRune(`[](){},"'=!<>#`, p.peek()) {
+				p.next()
+			}
+			if start == p.pos {
+				return "", p.errorf("Unexpected %q on condition", r)
+			}
+			tokens = append(tokens, string(p.input[start:p.pos]))
+		}
+	}
+}
+
+// joinExpressionTokens join the tokens with single space, except around brackets
+func joinExpressionTokens(tokens []string) string {
+	sb := &strings.Builder{}
+	for i, token := range tokens {
+		if i > 0 {
+			prev := tokens[i-1]
+			noSpace := prev == "[" || prev == "(" || prev == "!" ||
+				token == "]" || token == ")" || token == "," ||
+				(prev == "]" && token == "[")
+			if !noSpace {
+				sb.WriteString(" ")
+			}
+		}
+		sb.WriteString(token)
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,165 @@
+package kbhandler
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/disaster37/go-kibana-rest/v8/kbapi"
+	"github.com/stretchr/testify/assert"
+)
+
+const rawLogstashConfig = `
+# Read from beats
+input {
+  beats {
+    port => 5044
+    codec => json { charset => "UTF-8" }
+  }
+}
+
+filter {
+  if [type] == "nginx" and ![tags] {
+    grok {
+      match => { "message" => "%{COMBINEDAPACHELOG}" }
+      add_tag => ["nginx", 'web']
+    }
+  } else if [message] =~ /^ERROR/ {
+    mutate { add_field => { "level" => "error" } }
+  } else {
+    drop {}
+  }
+}
+
+output {
+  elasticsearch {
+    hosts => ["https://es:9200"]
+    ssl => true
+    index => "logs-%{+YYYY.MM.dd}"
+  }
+}
+`
+
+func TestParseLogstashConfig(t *testing.T) {
+
+	config, err := ParseLogstashConfig(rawLogstashConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, config.Sections, 3)
+	assert.Equal(t, "filter", config.Sections[1].Type)
+	branch := config.Sections[1].Statements[0].(*LogstashBranch)
+	assert.Len(t, branch.Conditions, 3)
+	assert.Equal(t, `[type] == "nginx" and ![tags]`, branch.Conditions[0].Expression)
+	assert.Equal(t, `[message] =~ /^ERROR/`, branch.Conditions[1].Expression)
+	assert.Equal(t, "", branch.Conditions[2].Expression)
+	plugin := config.Sections[0].Statements[0].(*LogstashPlugin)
+	assert.Equal(t, "beats", plugin.Name)
+	assert.Equal(t, 4, plugin.Line)
+	assert.Equal(t, LogstashNumber("5044"), plugin.Attributes[0].Value)
+	assert.Equal(t, "json", plugin.Attributes[1].Value.(*LogstashPlugin).Name)
+
+	// The canonical form can be parsed and is stable
+	config2, err := ParseLogstashConfig(config.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, config.String(), config2.String())
+
+	// When syntax error
+	_, err = ParseLogstashConfig("input {\n  stdin {\n    codec => \n  }\n}")
+	configErr := &LogstashConfigError{}
+	assert.True(t, errors.As(err, &configErr))
+	assert.Equal(t, 4, configErr.Line)
+	assert.Equal(t, 3, configErr.Column)
+
+	_, err = ParseLogstashConfig("inputs {}")
+	assert.EqualError(t, err, `line 1, column 1: Expected input, filter or output, got 'i'`)
+
+	_, err = ParseLogstashConfig("filter {\n  if [a] = 1 {}\n}")
+	assert.Error(t, err)
+
+	_, err = ParseLogstashConfig("output {\n  stdout {\n")
+	assert.Error(t, err)
+}
+
+func TestLogstashConfigEqual(t *testing.T) {
+	assert.True(t, LogstashConfigEqual(
+		"input { stdin { codec => json } } # comment",
+		"input {\n\tstdin {\n\t\tcodec => \"json\"\n\t}\n}\n",
+	))
+	assert.True(t, LogstashConfigEqual(
+		"filter { if [a][b]==\"x\" { drop {} } }",
+		"filter {\n  if [a][b] == 'x' {\n    drop {\n    }\n  }\n}",
+	))
+	assert.False(t, LogstashConfigEqual(
+		"input { stdin { codec => json } }",
+		"input { stdin { codec => plain } }",
+	))
+	assert.False(t, LogstashConfigEqual("input { stdin {", "input { stdin {}"))
+}
+
+func (t *KibanaHandlerTestSuite) TestLogstashPipelineSemanticDiff() {
+
+	handler := *t.kbHandler.(*KibanaHandlerImpl)
+	WithLogstashPipelineSemanticDiff()(&handler)
+
+	actual := &kbapi.LogstashPipeline{
+		ID:       "test",
+		Pipeline: "input {\n  stdin {}\n}\n# Edited from UI\noutput {\n  stdout { codec => rubydebug }\n}\n",
+	}
+	expected := &kbapi.LogstashPipeline{
+		ID:       "test",
+		Pipeline: "input { stdin {} }\noutput { stdout { codec => \"rubydebug\" } }",
+	}
+
+	// When only layout change
+	patchResult, err := handler.LogstashPipelineDiff(actual, expected, expected)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.True(t.T(), patchResult.IsEmpty())
+
+	// Without semantic diff
+	patchResult, err = t.kbHandler.LogstashPipelineDiff(actual, expected, expected)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.False(t.T(), patchResult.IsEmpty())
+
+	// When only description change, the actual config is kept
+	expected.Description = "my pipeline"
+	patchResult, err = handler.LogstashPipelineDiff(actual, expected, nil)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.False(t.T(), patchResult.IsEmpty())
+	assert.Equal(t.T(), actual.Pipeline, patchResult.Patched.(*kbapi.LogstashPipeline).Pipeline)
+	diff, err := RenderDiff(patchResult, nil)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Equal(t.T(), "--- actual\n+++ expected\n+ description: \"my pipeline\"\n", diff)
+
+	// When config change, the author's text is applied with its comments
+	expected.Description = ""
+	expected.Pipeline = "# Read from stdin\ninput { stdin {} }\noutput { stdout { codec => json } }"
+	patchResult, err = handler.LogstashPipelineDiff(actual, expected, nil)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.False(t.T(), patchResult.IsEmpty())
+	assert.Equal(t.T(), expected.Pipeline, patchResult.Patched.(*kbapi.LogstashPipeline).Pipeline)
+	changes := map[string]any{}
+	if err = json.Unmarshal(patchResult.Patch, &changes); err != nil {
+		panic(err)
+	}
+	assert.Equal(t.T(), map[string]any{"pipeline": expected.Pipeline}, changes)
+
+	// The rendered diff only show the semantic change
+	diff, err = RenderDiff(patchResult, nil)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Equal(t.T(), "--- actual\n+++ expected\n~ pipeline:\n      input {\n        stdin {}\n      }\n      \n      output {\n        stdout {\n-         codec => \"rubydebug\"\n+         codec => \"json\"\n        }\n      }\n      \n", diff)
+}
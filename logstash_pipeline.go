@@ -1,6 +1,9 @@
 package kbhandler
 
 import (
+	"fmt"
+	"net/http"
+
 	"github.com/disaster37/go-kibana-rest/v8/kbapi"
 	"github.com/disaster37/generic-objectmatcher/patch"
 	jsonIterator "github.com/json-iterator/go"
@@ -8,29 +11,101 @@ import (
 )
 
 // LogstashPipelineUpdate permit to create or update Logstash pipeline
+// When ownership is set, it's stamped as tag on pipeline description
+// When validation is enabled, the pipeline is validated before
 func (h *KibanaHandlerImpl) LogstashPipelineUpdate(pipeline *kbapi.LogstashPipeline) (err error) {
-	h.log.Debugf("Update Logstash pipeline %s", pipeline.ID)
+	h.log.Debug("Update Logstash pipeline", "scope", "logstash_pipeline", "name", pipeline.ID)
+	h, span := h.startSpan("LogstashPipelineUpdate", "logstash_pipeline", pipeline.ID, "")
+	defer span.end(&err)
+
+	if h.logstashValidation {
+		if err = h.LogstashPipelineValidate(pipeline); err != nil {
+			return err
+		}
+	}
+
+	if h.ownership != nil {
+		if err = h.checkLogstashPipelineOwner(pipeline.ID); err != nil {
+			return err
+		}
+		pipeline = h.stampLogstashPipeline(pipeline)
+	}
+
+	if h.isDryRun() {
+		return h.record(http.MethodPut, fmt.Sprintf("/api/logstash/pipeline/%s", pipeline.ID), &kbapi.LogstashPipelineRequest{
+			Description: pipeline.Description,
+			Pipeline:    pipeline.Pipeline,
+			Settings:    pipeline.Settings,
+		})
+	}
 
 	_, err = h.client.KibanaLogstashPipeline.CreateOrUpdate(pipeline)
-	return err
+	return wrapError(err)
 }
 
 // LogstashPipelineDelete permit to delete Logstash pipeline
 func (h *KibanaHandlerImpl) LogstashPipelineDelete(name string) (err error) {
-	h.log.Debugf("Delete Logstash pipeline %s", name)
+	h.log.Debug("Delete Logstash pipeline", "scope", "logstash_pipeline", "name", name)
+	h, span := h.startSpan("LogstashPipelineDelete", "logstash_pipeline", name, "")
+	defer span.end(&err)
+
+	if h.ownership != nil {
+		if err = h.checkLogstashPipelineOwner(name); err != nil {
+			return err
+		}
+	}
+
+	if h.isDryRun() {
+		return h.record(http.MethodDelete, fmt.Sprintf("/api/logstash/pipeline/%s", name), nil)
+	}
 
-	return h.client.KibanaLogstashPipeline.Delete(name)
+	return wrapError(h.client.KibanaLogstashPipeline.Delete(name))
 }
 
 // LogstashPipelineGet permit to get Logstash pipeline
+// It return nil if Logstash pipeline not exist
 func (h *KibanaHandlerImpl) LogstashPipelineGet(name string) (pipeline *kbapi.LogstashPipeline, err error) {
-	h.log.Debugf("Get Logstash pipeline %s", name)
+	h.log.Debug("Get Logstash pipeline", "scope", "logstash_pipeline", "name", name)
+	h, span := h.startSpan("LogstashPipelineGet", "logstash_pipeline", name, "")
+	defer span.end(&err)
 
-	return h.client.KibanaLogstashPipeline.Get(name)
+	pipeline, err = h.client.KibanaLogstashPipeline.Get(name)
+	return pipeline, wrapError(err)
+}
+
+// LogstashPipelineList permit to list Logstash pipelines
+// The name prefix is applied on pipeline ID
+func (h *KibanaHandlerImpl) LogstashPipelineList(opts *ListOptions) (pipelines kbapi.LogstashPipelines, err error) {
+	h.log.Debug("List Logstash pipelines", "scope", "logstash_pipeline")
+	h, span := h.startSpan("LogstashPipelineList", "logstash_pipeline", "", "")
+	defer span.end(&err)
+
+	allPipelines, err := h.client.KibanaLogstashPipeline.List()
+	if err != nil {
+		return nil, wrapError(err)
+	}
+
+	pipelines = make(kbapi.LogstashPipelines, 0, len(allPipelines))
+	for _, pipeline := range allPipelines {
+		if opts.matchName(pipeline.ID) {
+			pipelines = append(pipelines, pipeline)
+		}
+	}
+
+	return pipelines, nil
 }
 
 // LogstashPipelineDiff permit to diff Logstash pipeline
+// The diff rules of Logstash pipelines are applied before compute the diff
+// With semantic diff, the pipeline configs are compared without take care of comments and layout.
+// The patched object keep the actual config when configs are equivalent, else it take the expected config as written by its author
 func (h *KibanaHandlerImpl) LogstashPipelineDiff(actualObject, expectedObject, originalObject *kbapi.LogstashPipeline) (patchResult *patch.PatchResult, err error) {
+	defer h.observeDiff("logstash_pipeline", actualObject == nil, &patchResult)
+
+	if h.ownership != nil {
+		expectedObject = h.stampLogstashPipeline(expectedObject)
+	}
+
 	// If not yet exist
 	if actualObject == nil {
 		expected, err := jsonIterator.ConfigCompatibleWithStandardLibrary.Marshal(expectedObject)
@@ -47,5 +122,92 @@ func (h *KibanaHandlerImpl) LogstashPipelineDiff(actualObject, expectedObject, o
 		}, nil
 	}
 
-	return patch.DefaultPatchMaker.Calculate(actualObject, expectedObject, originalObject)
+	if !h.logstashSemanticDiff {
+		return h.calculate(DiffKindLogstashPipeline, actualObject, expectedObject, originalObject)
+	}
+
+	patchResult, err = h.calculate(DiffKindLogstashPipeline, actualObject, expectedObject, originalObject, canonicalLogstashPipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	// The canonical form is only used to compare, so the author's text is applied when config change
+	changes := map[string]any{}
+	if err = jsonIterator.ConfigCompatibleWithStandardLibrary.Unmarshal(patchResult.Patch, &changes); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode patch")
+	}
+	patched := patchResult.Patched.(*kbapi.LogstashPipeline)
+	if _, isChanged := changes["pipeline"].(string); isChanged {
+		changes["pipeline"] = expectedObject.Pipeline
+		if patchResult.Patch, err = jsonIterator.ConfigCompatibleWithStandardLibrary.Marshal(changes); err != nil {
+			return nil, errors.Wrap(err, "Failed to convert patch to byte sequence")
+		}
+		patched.Pipeline = expectedObject.Pipeline
+	} else {
+		patched.Pipeline = actualObject.Pipeline
+	}
+
+	return patchResult, nil
+}
+
+// WithLogstashPipelineSemanticDiff permit to compare the Logstash pipeline configs by their AST on LogstashPipelineDiff
+// So the changes of comments, whitespaces or indentation are not reported
+func WithLogstashPipelineSemanticDiff() Option {
+	return func(h *KibanaHandlerImpl) {
+		h.logstashSemanticDiff = true
+	}
+}
+
+// canonicalLogstashPipeline replace the pipeline configs by their canonical form
+// The invalid configs are kept as is
+func canonicalLogstashPipeline(current, modified []byte) ([]byte, []byte, error) {
+	canonical := func(data []byte) ([]byte, error) {
+		object := map[string]any{}
+		if err := jsonIterator.ConfigCompatibleWithStandardLibrary.Unmarshal(data, &object); err != nil {
+			return nil, err
+		}
+		pipeline, ok := object["pipeline"].(string)
+		if !ok {
+			return data, nil
+		}
+		config, err := ParseLogstashConfig(pipeline)
+		if err != nil {
+			return data, nil
+		}
+		object["pipeline"] = config.String()
+
+		return jsonIterator.ConfigCompatibleWithStandardLibrary.Marshal(object)
+	}
+
+	current, err := canonical(current)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Failed to normalize current pipeline")
+	}
+	modified, err = canonical(modified)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Failed to normalize expected pipeline")
+	}
+
+	return current, modified, nil
+}
+
+// stampLogstashPipeline return a copy of Logstash pipeline with the ownership tag on description
+func (h *KibanaHandlerImpl) stampLogstashPipeline(pipeline *kbapi.LogstashPipeline) *kbapi.LogstashPipeline {
+	stamped := *pipeline
+	stamped.Description = h.ownership.stampDescription(pipeline.Description)
+
+	return &stamped
+}
+
+// checkLogstashPipelineOwner return an error if Logstash pipeline is owned by another manager
+func (h *KibanaHandlerImpl) checkLogstashPipelineOwner(name string) (err error) {
+	current, err := h.LogstashPipelineGet(name)
+	if err != nil {
+		return errors.Wrapf(err, "Error when get Logstash pipeline %s", name)
+	}
+	if current == nil {
+		return nil
+	}
+
+	return h.checkOwner("Logstash pipeline", name, h.ownership.descriptionOwner(current.Description))
 }
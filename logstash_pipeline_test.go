@@ -215,3 +215,94 @@ func (t *KibanaHandlerTestSuite) TestLogstashPipelineDiff() {
 	assert.Equal(t.T(), actual, diff.Patched)
 
 }
+
+func (t *KibanaHandlerTestSuite) TestLogstashPipelineList() {
+
+	url := fmt.Sprintf("%s/api/logstash/pipelines", baseURL)
+
+	rawPipelines := `
+{
+	"pipelines": [
+		{
+			"id": "team-a-syslog",
+			"description": "Syslog of team A",
+			"last_modified": "2023-01-01T00:00:00.000Z",
+			"username": "elastic"
+		},
+		{
+			"id": "main",
+			"description": "Main pipeline",
+			"last_modified": "2023-01-01T00:00:00.000Z",
+			"username": "elastic"
+		}
+	]
+}
+	`
+
+	httpmock.RegisterResponder("GET", url, httpmock.NewStringResponder(200, rawPipelines))
+
+	// Without filter
+	pipelines, err := t.kbHandler.LogstashPipelineList(nil)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Len(t.T(), pipelines, 2)
+
+	// With name prefix
+	pipelines, err = t.kbHandler.LogstashPipelineList(&ListOptions{NamePrefix: "team-a"})
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Len(t.T(), pipelines, 1)
+	assert.Equal(t.T(), "team-a-syslog", pipelines[0].ID)
+
+	// When error
+	httpmock.RegisterResponder("GET", url, httpmock.NewErrorResponder(errors.New("fack error")))
+	_, err = t.kbHandler.LogstashPipelineList(nil)
+	assert.Error(t.T(), err)
+}
+
+func (t *KibanaHandlerTestSuite) TestLogstashPipelineValidate() {
+
+	pipeline := &kbapi.LogstashPipeline{
+		ID:       "test",
+		Pipeline: "input { stdin {} }\noutput { stdout {} }",
+		Settings: map[string]interface{}{
+			"pipeline.workers":    float64(2),
+			"pipeline.batch.size": float64(125),
+			"queue.type":          "persisted",
+			"queue.max_bytes":     "1gb",
+		},
+	}
+
+	// When pipeline is valid
+	err := t.kbHandler.LogstashPipelineValidate(pipeline)
+	assert.NoError(t.T(), err)
+
+	// When pipeline is invalid
+	pipeline.Pipeline = "input { stdin {} }\noutput {\n  stdout { codec => }\n}"
+	pipeline.Settings = map[string]interface{}{
+		"pipeline.workers": float64(0),
+		"queue.type":       "disk",
+		"queue.max_bytes":  "1 giga",
+		"pipeline.unknown": true,
+	}
+	err = t.kbHandler.LogstashPipelineValidate(pipeline)
+	validationErr := &LogstashPipelineValidationError{}
+	assert.True(t.T(), errors.As(err, &validationErr))
+	assert.Len(t.T(), validationErr.Errors, 5)
+	configErr := &LogstashConfigError{}
+	assert.True(t.T(), errors.As(err, &configErr))
+	assert.Equal(t.T(), 3, configErr.Line)
+	assert.Equal(t.T(), 21, configErr.Column)
+	assert.Contains(t.T(), err.Error(), "setting pipeline.unknown is unknown")
+	assert.Contains(t.T(), err.Error(), "setting pipeline.workers must be greater or equal to 1, got 0")
+
+	// When validation is enabled on update, invalid pipeline is not sent
+	handler := *t.kbHandler.(*KibanaHandlerImpl)
+	WithLogstashPipelineValidation()(&handler)
+	httpmock.RegisterResponder("PUT", urlLogstashPipeline, httpmock.NewStringResponder(204, ""))
+	err = handler.LogstashPipelineUpdate(pipeline)
+	assert.Error(t.T(), err)
+	assert.Equal(t.T(), 0, httpmock.GetTotalCallCount())
+}
@@ -0,0 +1,134 @@
+package kbhandler
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/disaster37/go-kibana-rest/v8/kbapi"
+)
+
+// LogstashPipelineValidationError is returned by LogstashPipelineValidate with all the problems found on pipeline
+// The config syntax error can be read with errors.As and LogstashConfigError
+type LogstashPipelineValidationError struct {
+	ID     string
+	Errors []error
+}
+
+func (e *LogstashPipelineValidationError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		messages = append(messages, err.Error())
+	}
+
+	return fmt.Sprintf("Logstash pipeline %s is invalid: %s", e.ID, strings.Join(messages, "; "))
+}
+
+func (e *LogstashPipelineValidationError) Unwrap() []error {
+	return e.Errors
+}
+
+// byteSizeRegexp match the byte size like 1024mb or 4gb
+var byteSizeRegexp = regexp.MustCompile(`^(?i)[0-9]+(b|kb|mb|gb|tb|pb)$`)
+
+// logstashPipelineSettings are the settings allowed by Kibana on centralized pipeline, with their validation
+var logstashPipelineSettings = map[string]func(value any) error{
+	"pipeline.workers":        validateInteger(1),
+	"pipeline.batch.size":     validateInteger(1),
+	"pipeline.batch.delay":    validateInteger(0),
+	"queue.checkpoint.writes": validateInteger(0),
+	"queue.type": func(value any) error {
+		if value != "memory" && value != "persisted" {
+			return fmt.Errorf("must be memory or persisted, got %v", value)
+		}
+		return nil
+	},
+	"queue.max_bytes": func(value any) error {
+		if s, ok := value.(string); ok && byteSizeRegexp.MatchString(s) {
+			return nil
+		}
+		if err := validateInteger(1)(value); err == nil {
+			return nil
+		}
+		return fmt.Errorf("must be a byte size like 1024mb or 1gb, got %v", value)
+	},
+}
+
+// validateInteger return the function that check the value is an integer greater or equal to minimum
+func validateInteger(minimum int64) func(value any) error {
+	return func(value any) error {
+		var i int64
+		switch v := value.(type) {
+		case int:
+			i = int64(v)
+		case int64:
+			i = v
+		case float64:
+			if v != math.Trunc(v) {
+				return fmt.Errorf("must be an integer, got %v", value)
+			}
+			i = int64(v)
+		case string:
+			var err error
+			if i, err = strconv.ParseInt(v, 10, 64); err != nil {
+				return fmt.Errorf("must be an integer, got %q", v)
+			}
+		default:
+			return fmt.Errorf("must be an integer, got %v", value)
+		}
+
+		if i < minimum {
+			return fmt.Errorf("must be greater or equal to %d, got %d", minimum, i)
+		}
+
+		return nil
+	}
+}
+
+// LogstashPipelineValidate permit to check Logstash pipeline offline, before send it to Kibana
+// It check the config syntax and the settings, and return LogstashPipelineValidationError with all problems found
+func (h *KibanaHandlerImpl) LogstashPipelineValidate(pipeline *kbapi.LogstashPipeline) (err error) {
+	h.log.Debug("Validate Logstash pipeline", "scope", "logstash_pipeline", "name", pipeline.ID)
+	h, span := h.startSpan("LogstashPipelineValidate", "logstash_pipeline", pipeline.ID, "")
+	defer span.end(&err)
+
+	validationError := &LogstashPipelineValidationError{ID: pipeline.ID}
+
+	if strings.TrimSpace(pipeline.Pipeline) == "" {
+		validationError.Errors = append(validationError.Errors, fmt.Errorf("pipeline is empty"))
+	} else if _, err = ParseLogstashConfig(pipeline.Pipeline); err != nil {
+		validationError.Errors = append(validationError.Errors, err)
+	}
+
+	keys := make([]string, 0, len(pipeline.Settings))
+	for key := range pipeline.Settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		validate, ok := logstashPipelineSettings[key]
+		if !ok {
+			validationError.Errors = append(validationError.Errors, fmt.Errorf("setting %s is unknown", key))
+			continue
+		}
+		if err = validate(pipeline.Settings[key]); err != nil {
+			validationError.Errors = append(validationError.Errors, fmt.Errorf("setting %s %s", key, err.Error()))
+		}
+	}
+
+	if len(validationError.Errors) > 0 {
+		return validationError
+	}
+
+	return nil
+}
+
+// WithLogstashPipelineValidation permit to call LogstashPipelineValidate before create or update Logstash pipeline
+func WithLogstashPipelineValidation() Option {
+	return func(h *KibanaHandlerImpl) {
+		h.logstashValidation = true
+	}
+}
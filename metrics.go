@@ -0,0 +1,172 @@
+package kbhandler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/disaster37/generic-objectmatcher/patch"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics are the Prometheus collectors of handler
+// It need to be registered by the caller on its registry, and can be shared between handlers
+type Metrics struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	diffs    *prometheus.CounterVec
+	retries  *prometheus.CounterVec
+
+	// The rate limit collectors are recorded only when WithRateLimit is used
+	throttleWait *prometheus.CounterVec
+	queueDepth   prometheus.Gauge
+	inFlight     prometheus.Gauge
+}
+
+// NewMetrics return the Prometheus collectors of handler. The namespace is the prefix of metric names
+func NewMetrics(namespace string) *Metrics {
+	return &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "kibana",
+			Name:      "requests_total",
+			Help:      "Number of requests sent to Kibana",
+		}, []string{"scope", "operation", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "kibana",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of requests sent to Kibana",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"scope", "operation", "status"}),
+		diffs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "kibana",
+			Name:      "diffs_total",
+			Help:      "Number of diffs computed, by outcome (create, update or noop)",
+		}, []string{"scope", "outcome"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "kibana",
+			Name:      "retries_total",
+			Help:      "Number of requests retried",
+		}, []string{"scope", "operation"}),
+		throttleWait: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "kibana",
+			Name:      "throttle_wait_seconds_total",
+			Help:      "Time spent by requests to wait the rate limit, by limit (rate or concurrency)",
+		}, []string{"limit"}),
+		queueDepth: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "kibana",
+			Name:      "throttle_queue_depth",
+			Help:      "Number of requests waiting the rate limit",
+		}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "kibana",
+			Name:      "requests_in_flight",
+			Help:      "Number of requests in progress on Kibana",
+		}),
+	}
+}
+
+// Describe implement prometheus.Collector
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.requests.Describe(ch)
+	m.duration.Describe(ch)
+	m.diffs.Describe(ch)
+	m.retries.Describe(ch)
+	m.throttleWait.Describe(ch)
+	m.queueDepth.Describe(ch)
+	m.inFlight.Describe(ch)
+}
+
+// Collect implement prometheus.Collector
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.requests.Collect(ch)
+	m.duration.Collect(ch)
+	m.diffs.Collect(ch)
+	m.retries.Collect(ch)
+	m.throttleWait.Collect(ch)
+	m.queueDepth.Collect(ch)
+	m.inFlight.Collect(ch)
+}
+
+// WithMetrics permit to record the metrics of handler on the Prometheus collectors
+func WithMetrics(metrics *Metrics) Option {
+	return func(h *KibanaHandlerImpl) {
+		h.metrics = metrics
+	}
+}
+
+// observeDiff record the outcome of diff
+func (h *KibanaHandlerImpl) observeDiff(scope string, created bool, patchResult **patch.PatchResult) {
+	if h.metrics == nil || *patchResult == nil {
+		return
+	}
+
+	outcome := "noop"
+	switch {
+	case created:
+		outcome = "create"
+	case !(*patchResult).IsEmpty():
+		outcome = "update"
+	}
+	h.metrics.diffs.WithLabelValues(scope, outcome).Inc()
+}
+
+// observeRetry record the retry of request
+func (m *Metrics) observeRetry(req *http.Request) {
+	if m == nil {
+		return
+	}
+	info := parseRequest(req)
+	m.retries.WithLabelValues(info.scope, info.operation).Inc()
+}
+
+// observeThrottleWait record the time spent by request to wait the limit
+func (m *Metrics) observeThrottleWait(limit string, wait time.Duration) {
+	if m == nil {
+		return
+	}
+	m.throttleWait.WithLabelValues(limit).Add(wait.Seconds())
+}
+
+// observeQueueDepth record the change of number of requests waiting the rate limit
+func (m *Metrics) observeQueueDepth(delta float64) {
+	if m == nil {
+		return
+	}
+	m.queueDepth.Add(delta)
+}
+
+// observeInFlight record the change of number of requests in progress
+func (m *Metrics) observeInFlight(delta float64) {
+	if m == nil {
+		return
+	}
+	m.inFlight.Add(delta)
+}
+
+// metricsTransport is the HTTP transport that record the requests
+type metricsTransport struct {
+	next    http.RoundTripper
+	metrics *Metrics
+}
+
+func (t *metricsTransport) RoundTrip(req *http.Request) (resp *http.Response, err error) {
+	start := time.Now()
+	resp, err = t.next.RoundTrip(req)
+
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+	info := parseRequest(req)
+	t.metrics.requests.WithLabelValues(info.scope, info.operation, status).Inc()
+	t.metrics.duration.WithLabelValues(info.scope, info.operation, status).Observe(time.Since(start).Seconds())
+
+	return resp, err
+}
@@ -0,0 +1,103 @@
+package kbhandler
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/disaster37/go-kibana-rest/v8/kbapi"
+	"github.com/jarcoal/httpmock"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func (t *KibanaHandlerTestSuite) TestMetrics() {
+
+	registry := prometheus.NewRegistry()
+	metrics := NewMetrics("test")
+	registry.MustRegister(metrics)
+
+	handler := *t.kbHandler.(*KibanaHandlerImpl)
+	WithMetrics(metrics)(&handler)
+	WithRetryPolicy(RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond, RetryableStatusCodes: []int{503}})(&handler)
+	handler.setupTransport()
+
+	// Requests and retries
+	calls := 0
+	httpmock.RegisterResponder("GET", urlrole, func(req *http.Request) (*http.Response, error) {
+		calls++
+		if calls == 1 {
+			return httpmock.NewStringResponse(503, ""), nil
+		}
+		return httpmock.NewStringResponse(200, `{"name": "test"}`), nil
+	})
+	httpmock.RegisterResponder("DELETE", fmt.Sprintf("%s/s/test/api/actions/connector/test", baseURL), httpmock.NewStringResponder(204, ""))
+
+	_, err := handler.RoleGet("test")
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	err = handler.ConnectorDelete("test", "test")
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Equal(t.T(), float64(1), testutil.ToFloat64(metrics.requests.WithLabelValues("role", "get", "503")))
+	assert.Equal(t.T(), float64(1), testutil.ToFloat64(metrics.requests.WithLabelValues("role", "get", "200")))
+	assert.Equal(t.T(), float64(1), testutil.ToFloat64(metrics.requests.WithLabelValues("connector", "delete", "204")))
+	assert.Equal(t.T(), float64(1), testutil.ToFloat64(metrics.retries.WithLabelValues("role", "get")))
+	assert.Equal(t.T(), 3, testutil.CollectAndCount(metrics.duration))
+
+	// Diff outcomes
+	expected := &kbapi.KibanaRole{Name: "test", Elasticsearch: &kbapi.KibanaRoleElasticsearch{Cluster: []string{"monitor"}}}
+	_, err = handler.RoleDiff(nil, expected, nil)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	_, err = handler.RoleDiff(expected, expected, nil)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	_, err = handler.RoleDiff(&kbapi.KibanaRole{Name: "test"}, expected, nil)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Equal(t.T(), float64(1), testutil.ToFloat64(metrics.diffs.WithLabelValues("role", "create")))
+	assert.Equal(t.T(), float64(1), testutil.ToFloat64(metrics.diffs.WithLabelValues("role", "noop")))
+	assert.Equal(t.T(), float64(1), testutil.ToFloat64(metrics.diffs.WithLabelValues("role", "update")))
+}
+
+func (t *KibanaHandlerTestSuite) TestMetricsWithRateLimit() {
+
+	registry := prometheus.NewRegistry()
+	metrics := NewMetrics("test")
+	registry.MustRegister(metrics)
+
+	handler := *t.kbHandler.(*KibanaHandlerImpl)
+	WithMetrics(metrics)(&handler)
+	WithRateLimit(RateLimit{RequestsPerSecond: 100, Burst: 1, MaxInFlight: 1})(&handler)
+	handler.setupTransport()
+
+	httpmock.RegisterResponder("GET", urlrole, httpmock.NewStringResponder(200, `{"name": "test"}`))
+	for i := 0; i < 3; i++ {
+		if _, err := handler.RoleGet("test"); err != nil {
+			t.Fail(err.Error())
+		}
+	}
+
+	assert.Greater(t.T(), testutil.ToFloat64(metrics.throttleWait.WithLabelValues("rate")), float64(0))
+	assert.Equal(t.T(), 2, testutil.CollectAndCount(metrics.throttleWait))
+	assert.Equal(t.T(), float64(0), testutil.ToFloat64(metrics.queueDepth))
+	assert.Equal(t.T(), float64(0), testutil.ToFloat64(metrics.inFlight))
+	assert.Equal(t.T(), int64(0), handler.RateLimitStats().Waiting)
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	names := make([]string, 0, len(families))
+	for _, family := range families {
+		names = append(names, family.GetName())
+	}
+	assert.Subset(t.T(), names, []string{"test_kibana_throttle_wait_seconds_total", "test_kibana_throttle_queue_depth", "test_kibana_requests_in_flight"})
+}
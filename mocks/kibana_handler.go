@@ -9,11 +9,13 @@
 package mocks
 
 import (
+	context "context"
 	reflect "reflect"
 
 	patch "github.com/disaster37/generic-objectmatcher/patch"
 	kibana "github.com/disaster37/go-kibana-rest/v8"
 	kbapi "github.com/disaster37/go-kibana-rest/v8/kbapi"
+	kbhandler "github.com/disaster37/kb-handler/v8"
 	logrus "github.com/sirupsen/logrus"
 	gomock "go.uber.org/mock/gomock"
 )
@@ -41,6 +43,120 @@ func (m *MockKibanaHandler) EXPECT() *MockKibanaHandlerMockRecorder {
 	return m.recorder
 }
 
+// AlertingRuleCreate mocks base method.
+func (m *MockKibanaHandler) AlertingRuleCreate(arg0 *kbhandler.AlertingRule, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AlertingRuleCreate", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AlertingRuleCreate indicates an expected call of AlertingRuleCreate.
+func (mr *MockKibanaHandlerMockRecorder) AlertingRuleCreate(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AlertingRuleCreate", reflect.TypeOf((*MockKibanaHandler)(nil).AlertingRuleCreate), arg0, arg1)
+}
+
+// AlertingRuleDelete mocks base method.
+func (m *MockKibanaHandler) AlertingRuleDelete(arg0, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AlertingRuleDelete", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AlertingRuleDelete indicates an expected call of AlertingRuleDelete.
+func (mr *MockKibanaHandlerMockRecorder) AlertingRuleDelete(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AlertingRuleDelete", reflect.TypeOf((*MockKibanaHandler)(nil).AlertingRuleDelete), arg0, arg1)
+}
+
+// AlertingRuleDiff mocks base method.
+func (m *MockKibanaHandler) AlertingRuleDiff(arg0, arg1, arg2 *kbhandler.AlertingRule) (*patch.PatchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AlertingRuleDiff", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*patch.PatchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AlertingRuleDiff indicates an expected call of AlertingRuleDiff.
+func (mr *MockKibanaHandlerMockRecorder) AlertingRuleDiff(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AlertingRuleDiff", reflect.TypeOf((*MockKibanaHandler)(nil).AlertingRuleDiff), arg0, arg1, arg2)
+}
+
+// AlertingRuleDisable mocks base method.
+func (m *MockKibanaHandler) AlertingRuleDisable(arg0, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AlertingRuleDisable", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AlertingRuleDisable indicates an expected call of AlertingRuleDisable.
+func (mr *MockKibanaHandlerMockRecorder) AlertingRuleDisable(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AlertingRuleDisable", reflect.TypeOf((*MockKibanaHandler)(nil).AlertingRuleDisable), arg0, arg1)
+}
+
+// AlertingRuleEnable mocks base method.
+func (m *MockKibanaHandler) AlertingRuleEnable(arg0, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AlertingRuleEnable", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AlertingRuleEnable indicates an expected call of AlertingRuleEnable.
+func (mr *MockKibanaHandlerMockRecorder) AlertingRuleEnable(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AlertingRuleEnable", reflect.TypeOf((*MockKibanaHandler)(nil).AlertingRuleEnable), arg0, arg1)
+}
+
+// AlertingRuleGet mocks base method.
+func (m *MockKibanaHandler) AlertingRuleGet(arg0, arg1 string) (*kbhandler.AlertingRule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AlertingRuleGet", arg0, arg1)
+	ret0, _ := ret[0].(*kbhandler.AlertingRule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AlertingRuleGet indicates an expected call of AlertingRuleGet.
+func (mr *MockKibanaHandlerMockRecorder) AlertingRuleGet(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AlertingRuleGet", reflect.TypeOf((*MockKibanaHandler)(nil).AlertingRuleGet), arg0, arg1)
+}
+
+// AlertingRuleMuteAll mocks base method.
+func (m *MockKibanaHandler) AlertingRuleMuteAll(arg0, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AlertingRuleMuteAll", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AlertingRuleMuteAll indicates an expected call of AlertingRuleMuteAll.
+func (mr *MockKibanaHandlerMockRecorder) AlertingRuleMuteAll(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AlertingRuleMuteAll", reflect.TypeOf((*MockKibanaHandler)(nil).AlertingRuleMuteAll), arg0, arg1)
+}
+
+// AlertingRuleUpdate mocks base method.
+func (m *MockKibanaHandler) AlertingRuleUpdate(arg0 *kbhandler.AlertingRule, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AlertingRuleUpdate", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AlertingRuleUpdate indicates an expected call of AlertingRuleUpdate.
+func (mr *MockKibanaHandlerMockRecorder) AlertingRuleUpdate(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AlertingRuleUpdate", reflect.TypeOf((*MockKibanaHandler)(nil).AlertingRuleUpdate), arg0, arg1)
+}
+
 // Client mocks base method.
 func (m *MockKibanaHandler) Client() *kibana.Client {
 	m.ctrl.T.Helper()
@@ -55,6 +171,351 @@ func (mr *MockKibanaHandlerMockRecorder) Client() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Client", reflect.TypeOf((*MockKibanaHandler)(nil).Client))
 }
 
+// ConnectorCreate mocks base method.
+func (m *MockKibanaHandler) ConnectorCreate(arg0 *kbhandler.Connector, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConnectorCreate", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ConnectorCreate indicates an expected call of ConnectorCreate.
+func (mr *MockKibanaHandlerMockRecorder) ConnectorCreate(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConnectorCreate", reflect.TypeOf((*MockKibanaHandler)(nil).ConnectorCreate), arg0, arg1)
+}
+
+// ConnectorDelete mocks base method.
+func (m *MockKibanaHandler) ConnectorDelete(arg0, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConnectorDelete", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ConnectorDelete indicates an expected call of ConnectorDelete.
+func (mr *MockKibanaHandlerMockRecorder) ConnectorDelete(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConnectorDelete", reflect.TypeOf((*MockKibanaHandler)(nil).ConnectorDelete), arg0, arg1)
+}
+
+// ConnectorDiff mocks base method.
+func (m *MockKibanaHandler) ConnectorDiff(arg0, arg1, arg2 *kbhandler.Connector) (*patch.PatchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConnectorDiff", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*patch.PatchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConnectorDiff indicates an expected call of ConnectorDiff.
+func (mr *MockKibanaHandlerMockRecorder) ConnectorDiff(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConnectorDiff", reflect.TypeOf((*MockKibanaHandler)(nil).ConnectorDiff), arg0, arg1, arg2)
+}
+
+// ConnectorGet mocks base method.
+func (m *MockKibanaHandler) ConnectorGet(arg0, arg1 string) (*kbhandler.Connector, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConnectorGet", arg0, arg1)
+	ret0, _ := ret[0].(*kbhandler.Connector)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConnectorGet indicates an expected call of ConnectorGet.
+func (mr *MockKibanaHandlerMockRecorder) ConnectorGet(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConnectorGet", reflect.TypeOf((*MockKibanaHandler)(nil).ConnectorGet), arg0, arg1)
+}
+
+// ConnectorUpdate mocks base method.
+func (m *MockKibanaHandler) ConnectorUpdate(arg0 *kbhandler.Connector, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConnectorUpdate", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ConnectorUpdate indicates an expected call of ConnectorUpdate.
+func (mr *MockKibanaHandlerMockRecorder) ConnectorUpdate(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConnectorUpdate", reflect.TypeOf((*MockKibanaHandler)(nil).ConnectorUpdate), arg0, arg1)
+}
+
+// DashboardExport mocks base method.
+func (m *MockKibanaHandler) DashboardExport(arg0, arg1 string) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DashboardExport", arg0, arg1)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DashboardExport indicates an expected call of DashboardExport.
+func (mr *MockKibanaHandlerMockRecorder) DashboardExport(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DashboardExport", reflect.TypeOf((*MockKibanaHandler)(nil).DashboardExport), arg0, arg1)
+}
+
+// DashboardImport mocks base method.
+func (m *MockKibanaHandler) DashboardImport(arg0 []byte, arg1 string, arg2, arg3 bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DashboardImport", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DashboardImport indicates an expected call of DashboardImport.
+func (mr *MockKibanaHandlerMockRecorder) DashboardImport(arg0, arg1, arg2, arg3 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DashboardImport", reflect.TypeOf((*MockKibanaHandler)(nil).DashboardImport), arg0, arg1, arg2, arg3)
+}
+
+// DataViewCreate mocks base method.
+func (m *MockKibanaHandler) DataViewCreate(arg0 *kbhandler.DataView, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DataViewCreate", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DataViewCreate indicates an expected call of DataViewCreate.
+func (mr *MockKibanaHandlerMockRecorder) DataViewCreate(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DataViewCreate", reflect.TypeOf((*MockKibanaHandler)(nil).DataViewCreate), arg0, arg1)
+}
+
+// DataViewDelete mocks base method.
+func (m *MockKibanaHandler) DataViewDelete(arg0, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DataViewDelete", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DataViewDelete indicates an expected call of DataViewDelete.
+func (mr *MockKibanaHandlerMockRecorder) DataViewDelete(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DataViewDelete", reflect.TypeOf((*MockKibanaHandler)(nil).DataViewDelete), arg0, arg1)
+}
+
+// DataViewDiff mocks base method.
+func (m *MockKibanaHandler) DataViewDiff(arg0, arg1, arg2 *kbhandler.DataView) (*patch.PatchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DataViewDiff", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*patch.PatchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DataViewDiff indicates an expected call of DataViewDiff.
+func (mr *MockKibanaHandlerMockRecorder) DataViewDiff(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DataViewDiff", reflect.TypeOf((*MockKibanaHandler)(nil).DataViewDiff), arg0, arg1, arg2)
+}
+
+// DataViewGet mocks base method.
+func (m *MockKibanaHandler) DataViewGet(arg0, arg1 string) (*kbhandler.DataView, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DataViewGet", arg0, arg1)
+	ret0, _ := ret[0].(*kbhandler.DataView)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DataViewGet indicates an expected call of DataViewGet.
+func (mr *MockKibanaHandlerMockRecorder) DataViewGet(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DataViewGet", reflect.TypeOf((*MockKibanaHandler)(nil).DataViewGet), arg0, arg1)
+}
+
+// DataViewUpdate mocks base method.
+func (m *MockKibanaHandler) DataViewUpdate(arg0 *kbhandler.DataView, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DataViewUpdate", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DataViewUpdate indicates an expected call of DataViewUpdate.
+func (mr *MockKibanaHandlerMockRecorder) DataViewUpdate(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DataViewUpdate", reflect.TypeOf((*MockKibanaHandler)(nil).DataViewUpdate), arg0, arg1)
+}
+
+// EnsurePackageInstalled mocks base method.
+func (m *MockKibanaHandler) EnsurePackageInstalled(arg0, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnsurePackageInstalled", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnsurePackageInstalled indicates an expected call of EnsurePackageInstalled.
+func (mr *MockKibanaHandlerMockRecorder) EnsurePackageInstalled(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnsurePackageInstalled", reflect.TypeOf((*MockKibanaHandler)(nil).EnsurePackageInstalled), arg0, arg1)
+}
+
+// FleetAgentPolicyCreate mocks base method.
+func (m *MockKibanaHandler) FleetAgentPolicyCreate(arg0 *kbhandler.FleetAgentPolicy) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FleetAgentPolicyCreate", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FleetAgentPolicyCreate indicates an expected call of FleetAgentPolicyCreate.
+func (mr *MockKibanaHandlerMockRecorder) FleetAgentPolicyCreate(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FleetAgentPolicyCreate", reflect.TypeOf((*MockKibanaHandler)(nil).FleetAgentPolicyCreate), arg0)
+}
+
+// FleetAgentPolicyDelete mocks base method.
+func (m *MockKibanaHandler) FleetAgentPolicyDelete(arg0 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FleetAgentPolicyDelete", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FleetAgentPolicyDelete indicates an expected call of FleetAgentPolicyDelete.
+func (mr *MockKibanaHandlerMockRecorder) FleetAgentPolicyDelete(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FleetAgentPolicyDelete", reflect.TypeOf((*MockKibanaHandler)(nil).FleetAgentPolicyDelete), arg0)
+}
+
+// FleetAgentPolicyDiff mocks base method.
+func (m *MockKibanaHandler) FleetAgentPolicyDiff(arg0, arg1, arg2 *kbhandler.FleetAgentPolicy) (*patch.PatchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FleetAgentPolicyDiff", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*patch.PatchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FleetAgentPolicyDiff indicates an expected call of FleetAgentPolicyDiff.
+func (mr *MockKibanaHandlerMockRecorder) FleetAgentPolicyDiff(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FleetAgentPolicyDiff", reflect.TypeOf((*MockKibanaHandler)(nil).FleetAgentPolicyDiff), arg0, arg1, arg2)
+}
+
+// FleetAgentPolicyGet mocks base method.
+func (m *MockKibanaHandler) FleetAgentPolicyGet(arg0 string) (*kbhandler.FleetAgentPolicy, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FleetAgentPolicyGet", arg0)
+	ret0, _ := ret[0].(*kbhandler.FleetAgentPolicy)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FleetAgentPolicyGet indicates an expected call of FleetAgentPolicyGet.
+func (mr *MockKibanaHandlerMockRecorder) FleetAgentPolicyGet(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FleetAgentPolicyGet", reflect.TypeOf((*MockKibanaHandler)(nil).FleetAgentPolicyGet), arg0)
+}
+
+// FleetAgentPolicyUpdate mocks base method.
+func (m *MockKibanaHandler) FleetAgentPolicyUpdate(arg0 *kbhandler.FleetAgentPolicy) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FleetAgentPolicyUpdate", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FleetAgentPolicyUpdate indicates an expected call of FleetAgentPolicyUpdate.
+func (mr *MockKibanaHandlerMockRecorder) FleetAgentPolicyUpdate(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FleetAgentPolicyUpdate", reflect.TypeOf((*MockKibanaHandler)(nil).FleetAgentPolicyUpdate), arg0)
+}
+
+// FleetPackagePolicyCreate mocks base method.
+func (m *MockKibanaHandler) FleetPackagePolicyCreate(arg0 *kbhandler.FleetPackagePolicy) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FleetPackagePolicyCreate", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FleetPackagePolicyCreate indicates an expected call of FleetPackagePolicyCreate.
+func (mr *MockKibanaHandlerMockRecorder) FleetPackagePolicyCreate(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FleetPackagePolicyCreate", reflect.TypeOf((*MockKibanaHandler)(nil).FleetPackagePolicyCreate), arg0)
+}
+
+// FleetPackagePolicyDelete mocks base method.
+func (m *MockKibanaHandler) FleetPackagePolicyDelete(arg0 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FleetPackagePolicyDelete", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FleetPackagePolicyDelete indicates an expected call of FleetPackagePolicyDelete.
+func (mr *MockKibanaHandlerMockRecorder) FleetPackagePolicyDelete(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FleetPackagePolicyDelete", reflect.TypeOf((*MockKibanaHandler)(nil).FleetPackagePolicyDelete), arg0)
+}
+
+// FleetPackagePolicyDiff mocks base method.
+func (m *MockKibanaHandler) FleetPackagePolicyDiff(arg0, arg1, arg2 *kbhandler.FleetPackagePolicy) (*patch.PatchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FleetPackagePolicyDiff", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*patch.PatchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FleetPackagePolicyDiff indicates an expected call of FleetPackagePolicyDiff.
+func (mr *MockKibanaHandlerMockRecorder) FleetPackagePolicyDiff(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FleetPackagePolicyDiff", reflect.TypeOf((*MockKibanaHandler)(nil).FleetPackagePolicyDiff), arg0, arg1, arg2)
+}
+
+// FleetPackagePolicyGet mocks base method.
+func (m *MockKibanaHandler) FleetPackagePolicyGet(arg0 string) (*kbhandler.FleetPackagePolicy, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FleetPackagePolicyGet", arg0)
+	ret0, _ := ret[0].(*kbhandler.FleetPackagePolicy)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FleetPackagePolicyGet indicates an expected call of FleetPackagePolicyGet.
+func (mr *MockKibanaHandlerMockRecorder) FleetPackagePolicyGet(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FleetPackagePolicyGet", reflect.TypeOf((*MockKibanaHandler)(nil).FleetPackagePolicyGet), arg0)
+}
+
+// FleetPackagePolicyUpdate mocks base method.
+func (m *MockKibanaHandler) FleetPackagePolicyUpdate(arg0 *kbhandler.FleetPackagePolicy) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FleetPackagePolicyUpdate", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FleetPackagePolicyUpdate indicates an expected call of FleetPackagePolicyUpdate.
+func (mr *MockKibanaHandlerMockRecorder) FleetPackagePolicyUpdate(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FleetPackagePolicyUpdate", reflect.TypeOf((*MockKibanaHandler)(nil).FleetPackagePolicyUpdate), arg0)
+}
+
+// Logger mocks base method.
+func (m *MockKibanaHandler) Logger() kbhandler.Logger {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Logger")
+	ret0, _ := ret[0].(kbhandler.Logger)
+	return ret0
+}
+
+// Logger indicates an expected call of Logger.
+func (mr *MockKibanaHandlerMockRecorder) Logger() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Logger", reflect.TypeOf((*MockKibanaHandler)(nil).Logger))
+}
+
 // LogstashPipelineDelete mocks base method.
 func (m *MockKibanaHandler) LogstashPipelineDelete(arg0 string) error {
 	m.ctrl.T.Helper()
@@ -99,6 +560,21 @@ func (mr *MockKibanaHandlerMockRecorder) LogstashPipelineGet(arg0 any) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LogstashPipelineGet", reflect.TypeOf((*MockKibanaHandler)(nil).LogstashPipelineGet), arg0)
 }
 
+// LogstashPipelineList mocks base method.
+func (m *MockKibanaHandler) LogstashPipelineList(arg0 *kbhandler.ListOptions) (kbapi.LogstashPipelines, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LogstashPipelineList", arg0)
+	ret0, _ := ret[0].(kbapi.LogstashPipelines)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LogstashPipelineList indicates an expected call of LogstashPipelineList.
+func (mr *MockKibanaHandlerMockRecorder) LogstashPipelineList(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LogstashPipelineList", reflect.TypeOf((*MockKibanaHandler)(nil).LogstashPipelineList), arg0)
+}
+
 // LogstashPipelineUpdate mocks base method.
 func (m *MockKibanaHandler) LogstashPipelineUpdate(arg0 *kbapi.LogstashPipeline) error {
 	m.ctrl.T.Helper()
@@ -113,6 +589,64 @@ func (mr *MockKibanaHandlerMockRecorder) LogstashPipelineUpdate(arg0 any) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LogstashPipelineUpdate", reflect.TypeOf((*MockKibanaHandler)(nil).LogstashPipelineUpdate), arg0)
 }
 
+// LogstashPipelineValidate mocks base method.
+func (m *MockKibanaHandler) LogstashPipelineValidate(arg0 *kbapi.LogstashPipeline) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LogstashPipelineValidate", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LogstashPipelineValidate indicates an expected call of LogstashPipelineValidate.
+func (mr *MockKibanaHandlerMockRecorder) LogstashPipelineValidate(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LogstashPipelineValidate", reflect.TypeOf((*MockKibanaHandler)(nil).LogstashPipelineValidate), arg0)
+}
+
+// Prune mocks base method.
+func (m *MockKibanaHandler) Prune(arg0 *kbhandler.PruneOptions) (*kbhandler.PrunePlan, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Prune", arg0)
+	ret0, _ := ret[0].(*kbhandler.PrunePlan)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Prune indicates an expected call of Prune.
+func (mr *MockKibanaHandlerMockRecorder) Prune(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Prune", reflect.TypeOf((*MockKibanaHandler)(nil).Prune), arg0)
+}
+
+// RateLimitStats mocks base method.
+func (m *MockKibanaHandler) RateLimitStats() kbhandler.RateLimitStats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RateLimitStats")
+	ret0, _ := ret[0].(kbhandler.RateLimitStats)
+	return ret0
+}
+
+// RateLimitStats indicates an expected call of RateLimitStats.
+func (mr *MockKibanaHandlerMockRecorder) RateLimitStats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RateLimitStats", reflect.TypeOf((*MockKibanaHandler)(nil).RateLimitStats))
+}
+
+// RoleCheckReferences mocks base method.
+func (m *MockKibanaHandler) RoleCheckReferences(arg0 *kbapi.KibanaRole) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RoleCheckReferences", arg0)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RoleCheckReferences indicates an expected call of RoleCheckReferences.
+func (mr *MockKibanaHandlerMockRecorder) RoleCheckReferences(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RoleCheckReferences", reflect.TypeOf((*MockKibanaHandler)(nil).RoleCheckReferences), arg0)
+}
+
 // RoleDelete mocks base method.
 func (m *MockKibanaHandler) RoleDelete(arg0 string) error {
 	m.ctrl.T.Helper()
@@ -157,6 +691,21 @@ func (mr *MockKibanaHandlerMockRecorder) RoleGet(arg0 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RoleGet", reflect.TypeOf((*MockKibanaHandler)(nil).RoleGet), arg0)
 }
 
+// RoleList mocks base method.
+func (m *MockKibanaHandler) RoleList(arg0 *kbhandler.ListOptions) (kbapi.KibanaRoles, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RoleList", arg0)
+	ret0, _ := ret[0].(kbapi.KibanaRoles)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RoleList indicates an expected call of RoleList.
+func (mr *MockKibanaHandlerMockRecorder) RoleList(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RoleList", reflect.TypeOf((*MockKibanaHandler)(nil).RoleList), arg0)
+}
+
 // RoleUpdate mocks base method.
 func (m *MockKibanaHandler) RoleUpdate(arg0 *kbapi.KibanaRole) error {
 	m.ctrl.T.Helper()
@@ -171,6 +720,106 @@ func (mr *MockKibanaHandlerMockRecorder) RoleUpdate(arg0 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RoleUpdate", reflect.TypeOf((*MockKibanaHandler)(nil).RoleUpdate), arg0)
 }
 
+// RoleValidate mocks base method.
+func (m *MockKibanaHandler) RoleValidate(arg0 *kbapi.KibanaRole) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RoleValidate", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RoleValidate indicates an expected call of RoleValidate.
+func (mr *MockKibanaHandlerMockRecorder) RoleValidate(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RoleValidate", reflect.TypeOf((*MockKibanaHandler)(nil).RoleValidate), arg0)
+}
+
+// SavedObjectCreate mocks base method.
+func (m *MockKibanaHandler) SavedObjectCreate(arg0 *kbhandler.SavedObject, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SavedObjectCreate", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SavedObjectCreate indicates an expected call of SavedObjectCreate.
+func (mr *MockKibanaHandlerMockRecorder) SavedObjectCreate(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SavedObjectCreate", reflect.TypeOf((*MockKibanaHandler)(nil).SavedObjectCreate), arg0, arg1)
+}
+
+// SavedObjectDelete mocks base method.
+func (m *MockKibanaHandler) SavedObjectDelete(arg0, arg1, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SavedObjectDelete", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SavedObjectDelete indicates an expected call of SavedObjectDelete.
+func (mr *MockKibanaHandlerMockRecorder) SavedObjectDelete(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SavedObjectDelete", reflect.TypeOf((*MockKibanaHandler)(nil).SavedObjectDelete), arg0, arg1, arg2)
+}
+
+// SavedObjectDiff mocks base method.
+func (m *MockKibanaHandler) SavedObjectDiff(arg0, arg1, arg2 *kbhandler.SavedObject) (*patch.PatchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SavedObjectDiff", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*patch.PatchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SavedObjectDiff indicates an expected call of SavedObjectDiff.
+func (mr *MockKibanaHandlerMockRecorder) SavedObjectDiff(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SavedObjectDiff", reflect.TypeOf((*MockKibanaHandler)(nil).SavedObjectDiff), arg0, arg1, arg2)
+}
+
+// SavedObjectGet mocks base method.
+func (m *MockKibanaHandler) SavedObjectGet(arg0, arg1, arg2 string) (*kbhandler.SavedObject, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SavedObjectGet", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*kbhandler.SavedObject)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SavedObjectGet indicates an expected call of SavedObjectGet.
+func (mr *MockKibanaHandlerMockRecorder) SavedObjectGet(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SavedObjectGet", reflect.TypeOf((*MockKibanaHandler)(nil).SavedObjectGet), arg0, arg1, arg2)
+}
+
+// SavedObjectUpdate mocks base method.
+func (m *MockKibanaHandler) SavedObjectUpdate(arg0 *kbhandler.SavedObject, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SavedObjectUpdate", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SavedObjectUpdate indicates an expected call of SavedObjectUpdate.
+func (mr *MockKibanaHandlerMockRecorder) SavedObjectUpdate(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SavedObjectUpdate", reflect.TypeOf((*MockKibanaHandler)(nil).SavedObjectUpdate), arg0, arg1)
+}
+
+// SetDefaultDataView mocks base method.
+func (m *MockKibanaHandler) SetDefaultDataView(arg0, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetDefaultDataView", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetDefaultDataView indicates an expected call of SetDefaultDataView.
+func (mr *MockKibanaHandlerMockRecorder) SetDefaultDataView(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDefaultDataView", reflect.TypeOf((*MockKibanaHandler)(nil).SetDefaultDataView), arg0, arg1)
+}
+
 // SetLogger mocks base method.
 func (m *MockKibanaHandler) SetLogger(arg0 *logrus.Entry) {
 	m.ctrl.T.Helper()
@@ -255,6 +904,21 @@ func (mr *MockKibanaHandlerMockRecorder) UserSpaceGet(arg0 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UserSpaceGet", reflect.TypeOf((*MockKibanaHandler)(nil).UserSpaceGet), arg0)
 }
 
+// UserSpaceList mocks base method.
+func (m *MockKibanaHandler) UserSpaceList(arg0 *kbhandler.ListOptions) (kbapi.KibanaSpaces, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UserSpaceList", arg0)
+	ret0, _ := ret[0].(kbapi.KibanaSpaces)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UserSpaceList indicates an expected call of UserSpaceList.
+func (mr *MockKibanaHandlerMockRecorder) UserSpaceList(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UserSpaceList", reflect.TypeOf((*MockKibanaHandler)(nil).UserSpaceList), arg0)
+}
+
 // UserSpaceUpdate mocks base method.
 func (m *MockKibanaHandler) UserSpaceUpdate(arg0 *kbapi.KibanaSpace) error {
 	m.ctrl.T.Helper()
@@ -268,3 +932,17 @@ func (mr *MockKibanaHandlerMockRecorder) UserSpaceUpdate(arg0 any) *gomock.Call
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UserSpaceUpdate", reflect.TypeOf((*MockKibanaHandler)(nil).UserSpaceUpdate), arg0)
 }
+
+// WithContext mocks base method.
+func (m *MockKibanaHandler) WithContext(arg0 context.Context) kbhandler.KibanaHandler {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WithContext", arg0)
+	ret0, _ := ret[0].(kbhandler.KibanaHandler)
+	return ret0
+}
+
+// WithContext indicates an expected call of WithContext.
+func (mr *MockKibanaHandlerMockRecorder) WithContext(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithContext", reflect.TypeOf((*MockKibanaHandler)(nil).WithContext), arg0)
+}
@@ -0,0 +1,107 @@
+package kbhandler
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/disaster37/go-kibana-rest/v8/kbapi"
+	"github.com/pkg/errors"
+)
+
+// Ownership identify the objects managed by a tool, like managed-by=kb-handler
+// It's stored on role metadata, and as tag on user space and Logstash pipeline description because they not have metadata
+type Ownership struct {
+	Key   string
+	Value string
+}
+
+// WithOwnership permit to stamp the ownership on roles, user spaces and Logstash pipelines written by the handler
+// The handler refuse to update or delete objects owned by another manager, except if force is true
+func WithOwnership(ownership Ownership, force bool) Option {
+	return func(h *KibanaHandlerImpl) {
+		h.ownership = &ownership
+		h.forceOwnership = force
+	}
+}
+
+// tag return the ownership tag added on description
+func (o Ownership) tag() string {
+	return fmt.Sprintf("[%s=%s]", o.Key, o.Value)
+}
+
+// tagRegexp return the regexp that match the ownership tag of any manager
+func (o Ownership) tagRegexp() *regexp.Regexp {
+	return regexp.MustCompile(`\s*\[` + regexp.QuoteMeta(o.Key) + `=([^\]]*)\]`)
+}
+
+// ownsRole return true if role is owned
+func (o Ownership) ownsRole(role *kbapi.KibanaRole) bool {
+	if role == nil {
+		return false
+	}
+
+	value, ok := role.Metadata[o.Key]
+	return ok && fmt.Sprint(value) == o.Value
+}
+
+// ownsDescription return true if description contain the ownership tag
+func (o Ownership) ownsDescription(description string) bool {
+	return strings.Contains(description, o.tag())
+}
+
+// roleOwner return the manager of role, or empty string if role is not managed
+func (o Ownership) roleOwner(role *kbapi.KibanaRole) string {
+	if role == nil {
+		return ""
+	}
+	value, ok := role.Metadata[o.Key]
+	if !ok {
+		return ""
+	}
+
+	return fmt.Sprint(value)
+}
+
+// descriptionOwner return the manager stored on description, or empty string if not managed
+func (o Ownership) descriptionOwner(description string) string {
+	match := o.tagRegexp().FindStringSubmatch(description)
+	if match == nil {
+		return ""
+	}
+
+	return match[1]
+}
+
+// stampRole return a copy of role with the ownership on metadata
+func (o Ownership) stampRole(role *kbapi.KibanaRole) *kbapi.KibanaRole {
+	stamped := *role
+	stamped.Metadata = make(map[string]interface{}, len(role.Metadata)+1)
+	for key, value := range role.Metadata {
+		stamped.Metadata[key] = value
+	}
+	stamped.Metadata[o.Key] = o.Value
+
+	return &stamped
+}
+
+// stampDescription return the description with the ownership tag
+// The tag of another manager is replaced
+func (o Ownership) stampDescription(description string) string {
+	description = o.tagRegexp().ReplaceAllString(description, "")
+	if description == "" {
+		return o.tag()
+	}
+
+	return fmt.Sprintf("%s %s", description, o.tag())
+}
+
+// checkOwner return an error if the object is owned by another manager
+// Objects without owner can be adopted
+func (h *KibanaHandlerImpl) checkOwner(kind, name, owner string) error {
+	if h.ownership == nil || h.forceOwnership || owner == "" || owner == h.ownership.Value {
+		return nil
+	}
+
+	return errors.Wrapf(ErrConflict, "%s %s is owned by %s=%s", kind, name, h.ownership.Key, owner)
+}
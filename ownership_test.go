@@ -0,0 +1,96 @@
+package kbhandler
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/disaster37/go-kibana-rest/v8/kbapi"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOwnershipStampDescription(t *testing.T) {
+	ownership := Ownership{Key: "managed-by", Value: "kb-handler"}
+
+	assert.Equal(t, "[managed-by=kb-handler]", ownership.stampDescription(""))
+	assert.Equal(t, "My space [managed-by=kb-handler]", ownership.stampDescription("My space"))
+	assert.Equal(t, "My space [managed-by=kb-handler]", ownership.stampDescription("My space [managed-by=kb-handler]"))
+	assert.Equal(t, "My space [managed-by=kb-handler]", ownership.stampDescription("My space [managed-by=terraform]"))
+	assert.Equal(t, "terraform", ownership.descriptionOwner("My space [managed-by=terraform]"))
+	assert.Equal(t, "", ownership.descriptionOwner("My space"))
+}
+
+func (t *KibanaHandlerTestSuite) TestOwnership() {
+
+	handler := *t.kbHandler.(*KibanaHandlerImpl)
+	WithOwnership(Ownership{Key: "managed-by", Value: "kb-handler"}, false)(&handler)
+	forcedHandler := handler
+	forcedHandler.forceOwnership = true
+
+	// When role not exist, ownership is stamped on metadata
+	var body string
+	httpmock.RegisterResponder("GET", urlrole, httpmock.NewStringResponder(404, `{}`))
+	httpmock.RegisterResponder("PUT", urlrole, func(req *http.Request) (*http.Response, error) {
+		b, _ := io.ReadAll(req.Body)
+		body = string(b)
+		httpmock.RegisterResponder("GET", urlrole, httpmock.NewStringResponder(200, `{"name": "test", "metadata": {"managed-by": "kb-handler"}}`))
+		return httpmock.NewStringResponse(204, ""), nil
+	})
+	role := &kbapi.KibanaRole{
+		Name:     "test",
+		Metadata: map[string]interface{}{"version": 1},
+	}
+	err := handler.RoleUpdate(role)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Contains(t.T(), body, `"managed-by":"kb-handler"`)
+	assert.NotContains(t.T(), role.Metadata, "managed-by")
+
+	// When role is owned by another manager
+	httpmock.RegisterResponder("GET", urlrole, httpmock.NewStringResponder(200, `{"name": "test", "metadata": {"managed-by": "terraform"}}`))
+	httpmock.RegisterResponder("DELETE", urlrole, httpmock.NewStringResponder(204, ""))
+	err = handler.RoleUpdate(role)
+	assert.ErrorIs(t.T(), err, ErrConflict)
+	err = handler.RoleDelete("test")
+	assert.ErrorIs(t.T(), err, ErrConflict)
+	assert.Equal(t.T(), 0, httpmock.GetCallCountInfo()["DELETE "+urlrole])
+
+	// When force
+	err = forcedHandler.RoleDelete("test")
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), 1, httpmock.GetCallCountInfo()["DELETE "+urlrole])
+
+	// When create user space, ownership is stamped on description
+	httpmock.RegisterResponder("POST", fmt.Sprintf("%s/api/spaces/space", baseURL), func(req *http.Request) (*http.Response, error) {
+		b, _ := io.ReadAll(req.Body)
+		body = string(b)
+		return httpmock.NewStringResponse(200, body), nil
+	})
+	err = handler.UserSpaceCreate(&kbapi.KibanaSpace{
+		ID:          "test",
+		Name:        "test",
+		Description: "My space",
+	})
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Contains(t.T(), body, `"description":"My space [managed-by=kb-handler]"`)
+
+	// When Logstash pipeline is owned by another manager
+	httpmock.RegisterResponder("GET", urlLogstashPipeline, httpmock.NewStringResponder(200, `{"id": "test", "description": "[managed-by=terraform]"}`))
+	err = handler.LogstashPipelineUpdate(&kbapi.LogstashPipeline{ID: "test"})
+	assert.ErrorIs(t.T(), err, ErrConflict)
+	err = handler.LogstashPipelineDelete("test")
+	assert.ErrorIs(t.T(), err, ErrConflict)
+
+	// When diff, ownership is part of expected object
+	actual := &kbapi.LogstashPipeline{ID: "test", Description: "My pipeline [managed-by=kb-handler]"}
+	patchResult, err := handler.LogstashPipelineDiff(actual, &kbapi.LogstashPipeline{ID: "test", Description: "My pipeline"}, nil)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.True(t.T(), patchResult.IsEmpty())
+}
@@ -0,0 +1,100 @@
+package kbhandler
+
+import (
+	"sync"
+
+	jsonIterator "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+)
+
+// PlanOperation is a request that the handler would send to Kibana
+type PlanOperation struct {
+	Method string
+	Path   string
+	Body   []byte
+}
+
+// Plan record the mutating requests when the handler run on dry run mode
+// It can be shared between goroutines
+type Plan struct {
+	mu         sync.Mutex
+	operations []PlanOperation
+}
+
+// NewPlan return an empty plan
+func NewPlan() *Plan {
+	return &Plan{}
+}
+
+// WithDryRun permit to record all mutating requests on plan instead to send them to Kibana
+// The read requests are still sent, so the diff and the ownership checks work as usual
+func WithDryRun(plan *Plan) Option {
+	return func(h *KibanaHandlerImpl) {
+		h.plan = plan
+	}
+}
+
+// Operations return a copy of recorded operations, by order of call
+func (p *Plan) Operations() []PlanOperation {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	operations := make([]PlanOperation, len(p.operations))
+	copy(operations, p.operations)
+
+	return operations
+}
+
+// IsEmpty return true if there are no operation on plan
+func (p *Plan) IsEmpty() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.operations) == 0
+}
+
+// Reset remove all recorded operations
+func (p *Plan) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.operations = nil
+}
+
+// add append operation on plan
+func (p *Plan) add(operation PlanOperation) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.operations = append(p.operations, operation)
+}
+
+// isDryRun return true if the handler record requests on plan
+func (h *KibanaHandlerImpl) isDryRun() bool {
+	return h.plan != nil
+}
+
+// record add the request on plan. The body is converted to JSON, except if it's already a byte sequence
+func (h *KibanaHandlerImpl) record(method, path string, body any) (err error) {
+	h.log.Debug("Dry run", "method", method, "path", path)
+
+	operation := PlanOperation{
+		Method: method,
+		Path:   path,
+	}
+
+	switch b := body.(type) {
+	case nil:
+	case []byte:
+		operation.Body = b
+	default:
+		operation.Body, err = jsonIterator.ConfigCompatibleWithStandardLibrary.Marshal(body)
+		if err != nil {
+			return errors.Wrap(err, "Failed to convert body to byte sequence")
+		}
+	}
+
+	h.plan.add(operation)
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package kbhandler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/disaster37/go-kibana-rest/v8/kbapi"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func (t *KibanaHandlerTestSuite) TestDryRun() {
+
+	plan := NewPlan()
+	handler := *t.kbHandler.(*KibanaHandlerImpl)
+	WithDryRun(plan)(&handler)
+
+	// No mutating request must be sent
+	httpmock.RegisterNoResponder(func(req *http.Request) (*http.Response, error) {
+		t.Fail(fmt.Sprintf("Unexpected request %s %s", req.Method, req.URL.String()))
+		return httpmock.NewStringResponse(500, ""), nil
+	})
+
+	err := handler.RoleUpdate(&kbapi.KibanaRole{
+		Name: "test",
+		Elasticsearch: &kbapi.KibanaRoleElasticsearch{
+			Cluster: []string{"monitor"},
+		},
+	})
+	assert.NoError(t.T(), err)
+	err = handler.RoleDelete("test")
+	assert.NoError(t.T(), err)
+	err = handler.UserSpaceCreate(&kbapi.KibanaSpace{ID: "test", Name: "test"})
+	assert.NoError(t.T(), err)
+	err = handler.UserSpaceUpdate(&kbapi.KibanaSpace{ID: "test", Name: "test"})
+	assert.NoError(t.T(), err)
+	err = handler.UserSpaceDelete("test")
+	assert.NoError(t.T(), err)
+	err = handler.UserSpaceCopyObject("test", &kbapi.KibanaSpaceCopySavedObjectParameter{Spaces: []string{"other"}})
+	assert.NoError(t.T(), err)
+	err = handler.LogstashPipelineUpdate(&kbapi.LogstashPipeline{ID: "test", Pipeline: "input { stdin {} }"})
+	assert.NoError(t.T(), err)
+	err = handler.LogstashPipelineDelete("test")
+	assert.NoError(t.T(), err)
+	err = handler.ConnectorDelete("test", "test")
+	assert.NoError(t.T(), err)
+
+	operations := plan.Operations()
+	expected := []PlanOperation{
+		{Method: "PUT", Path: "/api/security/role/test", Body: []byte(`{"elasticsearch":{"cluster":["monitor"]}}`)},
+		{Method: "DELETE", Path: "/api/security/role/test"},
+		{Method: "POST", Path: "/api/spaces/space", Body: []byte(`{"id":"test","name":"test"}`)},
+		{Method: "PUT", Path: "/api/spaces/space/test", Body: []byte(`{"id":"test","name":"test"}`)},
+		{Method: "DELETE", Path: "/api/spaces/space/test"},
+		{Method: "POST", Path: "/s/test/api/spaces/_copy_saved_objects", Body: []byte(`{"objects":null,"spaces":["other"],"includeReferences":false,"overwrite":false,"createNewCopies":false}`)},
+		{Method: "PUT", Path: "/api/logstash/pipeline/test", Body: []byte(`{"pipeline":"input { stdin {} }"}`)},
+		{Method: "DELETE", Path: "/api/logstash/pipeline/test"},
+		{Method: "DELETE", Path: "/s/test/api/actions/connector/test"},
+	}
+	assert.Equal(t.T(), len(expected), len(operations))
+	for i := range expected {
+		assert.Equal(t.T(), expected[i].Method, operations[i].Method)
+		assert.Equal(t.T(), expected[i].Path, operations[i].Path)
+		if expected[i].Body == nil {
+			assert.Nil(t.T(), operations[i].Body)
+		} else {
+			assert.JSONEq(t.T(), string(expected[i].Body), string(operations[i].Body))
+		}
+	}
+	assert.Equal(t.T(), 0, httpmock.GetTotalCallCount())
+
+	// The read requests are still sent
+	httpmock.RegisterResponder("GET", urlrole, httpmock.NewStringResponder(200, `{"name": "test"}`))
+	role, err := handler.RoleGet("test")
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Equal(t.T(), "test", role.Name)
+
+	// When reset
+	plan.Reset()
+	assert.True(t.T(), plan.IsEmpty())
+}
@@ -0,0 +1,127 @@
+package kbhandler
+
+import (
+	"github.com/pkg/errors"
+)
+
+// PruneOptions permit to set the objects to keep when prune
+// A nil list disable the prune of its scope, while an empty list delete all owned objects of the scope
+type PruneOptions struct {
+	// Ownership select the objects managed by us. Only them can be deleted
+	Ownership Ownership
+
+	// Roles is the name of desired roles
+	Roles []string
+
+	// UserSpaces is the ID of desired user spaces
+	UserSpaces []string
+
+	// LogstashPipelines is the ID of desired Logstash pipelines
+	LogstashPipelines []string
+
+	// DryRun return the deletion plan without delete objects
+	DryRun bool
+}
+
+// PrunePlan is the objects deleted by prune, or to delete on dry run
+type PrunePlan struct {
+	Roles             []string
+	UserSpaces        []string
+	LogstashPipelines []string
+}
+
+// IsEmpty return true if there are nothing to delete
+func (p *PrunePlan) IsEmpty() bool {
+	return len(p.Roles) == 0 && len(p.UserSpaces) == 0 && len(p.LogstashPipelines) == 0
+}
+
+// Prune permit to delete owned objects that are not on the desired list
+// Reserved objects are never deleted
+// When a deletion failed, it return the objects already deleted with the error
+func (h *KibanaHandlerImpl) Prune(opts *PruneOptions) (plan *PrunePlan, err error) {
+	if opts == nil || opts.Ownership.Key == "" {
+		return nil, errors.New("You need to provide ownership to prune objects")
+	}
+	h.log.Debug("Prune owned objects", "owner", opts.Ownership.tag())
+	h, span := h.startSpan("Prune", "prune", opts.Ownership.tag(), "")
+	defer span.end(&err)
+
+	plan = &PrunePlan{}
+	notReserved := false
+
+	if opts.LogstashPipelines != nil {
+		desired := toSet(opts.LogstashPipelines)
+		pipelines, err := h.LogstashPipelineList(nil)
+		if err != nil {
+			return nil, errors.Wrap(err, "Error when list Logstash pipelines")
+		}
+		for _, pipeline := range pipelines {
+			if !desired[pipeline.ID] && opts.Ownership.ownsDescription(pipeline.Description) {
+				plan.LogstashPipelines = append(plan.LogstashPipelines, pipeline.ID)
+			}
+		}
+	}
+
+	if opts.Roles != nil {
+		desired := toSet(opts.Roles)
+		roles, err := h.RoleList(&ListOptions{Reserved: &notReserved})
+		if err != nil {
+			return nil, errors.Wrap(err, "Error when list roles")
+		}
+		for i := range roles {
+			if !desired[roles[i].Name] && opts.Ownership.ownsRole(&roles[i]) {
+				plan.Roles = append(plan.Roles, roles[i].Name)
+			}
+		}
+	}
+
+	if opts.UserSpaces != nil {
+		desired := toSet(opts.UserSpaces)
+		userSpaces, err := h.UserSpaceList(&ListOptions{Reserved: &notReserved})
+		if err != nil {
+			return nil, errors.Wrap(err, "Error when list user spaces")
+		}
+		for _, userSpace := range userSpaces {
+			if !desired[userSpace.ID] && opts.Ownership.ownsDescription(userSpace.Description) {
+				plan.UserSpaces = append(plan.UserSpaces, userSpace.ID)
+			}
+		}
+	}
+
+	if opts.DryRun {
+		return plan, nil
+	}
+
+	// The objects are recorded once deleted, so the caller know what is done when a deletion failed
+	deleted := &PrunePlan{}
+	for _, id := range plan.LogstashPipelines {
+		if err = h.LogstashPipelineDelete(id); err != nil {
+			return deleted, errors.Wrapf(err, "Error when delete Logstash pipeline %s", id)
+		}
+		deleted.LogstashPipelines = append(deleted.LogstashPipelines, id)
+	}
+	for _, name := range plan.Roles {
+		if err = h.RoleDelete(name); err != nil {
+			return deleted, errors.Wrapf(err, "Error when delete role %s", name)
+		}
+		deleted.Roles = append(deleted.Roles, name)
+	}
+	for _, id := range plan.UserSpaces {
+		if err = h.UserSpaceDelete(id); err != nil {
+			return deleted, errors.Wrapf(err, "Error when delete user space %s", id)
+		}
+		deleted.UserSpaces = append(deleted.UserSpaces, id)
+	}
+
+	return deleted, nil
+}
+
+// toSet convert list to set
+func toSet(list []string) map[string]bool {
+	set := make(map[string]bool, len(list))
+	for _, item := range list {
+		set[item] = true
+	}
+
+	return set
+}
@@ -0,0 +1,149 @@
+package kbhandler
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func (t *KibanaHandlerTestSuite) TestPrune() {
+
+	rawRoles := `
+[
+	{
+		"name": "kibana_admin",
+		"metadata": {
+			"_reserved": true,
+			"managed-by": "kb-handler"
+		}
+	},
+	{
+		"name": "team-a-read",
+		"metadata": {
+			"managed-by": "kb-handler"
+		}
+	},
+	{
+		"name": "team-b-read",
+		"metadata": {
+			"managed-by": "kb-handler"
+		}
+	},
+	{
+		"name": "manual",
+		"metadata": {
+			"managed-by": "terraform"
+		}
+	}
+]
+	`
+	rawUserSpaces := `
+[
+	{
+		"id": "default",
+		"name": "Default",
+		"description": "[managed-by=kb-handler]",
+		"_reserved": true
+	},
+	{
+		"id": "team-a",
+		"name": "Team A",
+		"description": "Space of team A [managed-by=kb-handler]"
+	},
+	{
+		"id": "team-b",
+		"name": "Team B",
+		"description": "Space of team B [managed-by=kb-handler]"
+	},
+	{
+		"id": "manual",
+		"name": "Manual"
+	}
+]
+	`
+	rawPipelines := `
+{
+	"pipelines": [
+		{
+			"id": "team-a",
+			"description": "[managed-by=kb-handler]"
+		},
+		{
+			"id": "team-b",
+			"description": "[managed-by=kb-handler]"
+		},
+		{
+			"id": "manual",
+			"description": "Created by hand"
+		}
+	]
+}
+	`
+
+	httpmock.RegisterResponder("GET", fmt.Sprintf("%s/api/security/role", baseURL), httpmock.NewStringResponder(200, rawRoles))
+	httpmock.RegisterResponder("GET", fmt.Sprintf("%s/api/spaces/space", baseURL), httpmock.NewStringResponder(200, rawUserSpaces))
+	httpmock.RegisterResponder("GET", fmt.Sprintf("%s/api/logstash/pipelines", baseURL), httpmock.NewStringResponder(200, rawPipelines))
+	httpmock.RegisterResponder("DELETE", fmt.Sprintf("%s/api/security/role/team-b-read", baseURL), httpmock.NewStringResponder(204, ""))
+	httpmock.RegisterResponder("DELETE", fmt.Sprintf("%s/api/spaces/space/team-b", baseURL), httpmock.NewStringResponder(204, ""))
+	httpmock.RegisterResponder("DELETE", fmt.Sprintf("%s/api/logstash/pipeline/team-b", baseURL), httpmock.NewStringResponder(204, ""))
+
+	opts := &PruneOptions{
+		Ownership: Ownership{
+			Key:   "managed-by",
+			Value: "kb-handler",
+		},
+		Roles:             []string{"team-a-read"},
+		UserSpaces:        []string{"team-a"},
+		LogstashPipelines: []string{"team-a"},
+		DryRun:            true,
+	}
+	expectedPlan := &PrunePlan{
+		Roles:             []string{"team-b-read"},
+		UserSpaces:        []string{"team-b"},
+		LogstashPipelines: []string{"team-b"},
+	}
+
+	// When dry run
+	plan, err := t.kbHandler.Prune(opts)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Equal(t.T(), expectedPlan, plan)
+	assert.Equal(t.T(), 3, httpmock.GetTotalCallCount())
+
+	// When prune
+	opts.DryRun = false
+	plan, err = t.kbHandler.Prune(opts)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Equal(t.T(), expectedPlan, plan)
+	assert.Equal(t.T(), 1, httpmock.GetCallCountInfo()[fmt.Sprintf("DELETE %s/api/security/role/team-b-read", baseURL)])
+	assert.Equal(t.T(), 1, httpmock.GetCallCountInfo()[fmt.Sprintf("DELETE %s/api/spaces/space/team-b", baseURL)])
+	assert.Equal(t.T(), 1, httpmock.GetCallCountInfo()[fmt.Sprintf("DELETE %s/api/logstash/pipeline/team-b", baseURL)])
+
+	// When scope is not provided, it's not pruned
+	plan, err = t.kbHandler.Prune(&PruneOptions{
+		Ownership: opts.Ownership,
+		Roles:     []string{},
+		DryRun:    true,
+	})
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Equal(t.T(), []string{"team-a-read", "team-b-read"}, plan.Roles)
+	assert.Empty(t.T(), plan.UserSpaces)
+	assert.Empty(t.T(), plan.LogstashPipelines)
+
+	// When ownership is not provided
+	_, err = t.kbHandler.Prune(&PruneOptions{Roles: []string{}})
+	assert.Error(t.T(), err)
+
+	// When the second deletion failed, the objects already deleted are returned
+	httpmock.RegisterResponder("DELETE", fmt.Sprintf("%s/api/security/role/team-b-read", baseURL), httpmock.NewErrorResponder(errors.New("fack error")))
+	plan, err = t.kbHandler.Prune(opts)
+	assert.Error(t.T(), err)
+	assert.Equal(t.T(), &PrunePlan{LogstashPipelines: []string{"team-b"}}, plan)
+}
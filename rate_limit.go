@@ -0,0 +1,184 @@
+package kbhandler
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// RateLimit permit to protect Kibana when many objects are reconciled at the same time
+// It's shared by all the calls of the handler, and by the handlers created with WithContext
+type RateLimit struct {
+	// RequestsPerSecond is the rate of requests sent to Kibana. 0 disable the rate limit
+	RequestsPerSecond float64
+
+	// Burst is the number of requests that can be sent at once. Default to 1
+	Burst int
+
+	// MaxInFlight is the max number of requests sent in parallel. 0 disable the limit
+	MaxInFlight int
+}
+
+// RateLimitStats are the metrics of rate limit
+type RateLimitStats struct {
+	// Requests is the number of requests sent
+	Requests int64
+
+	// InFlight is the number of requests in progress
+	InFlight int64
+
+	// Waiting is the number of requests waiting the rate limit or a free slot
+	Waiting int64
+
+	// RateLimitWait is the total time spent to wait the rate limiter
+	RateLimitWait time.Duration
+
+	// ConcurrencyWait is the total time spent to wait a free slot
+	ConcurrencyWait time.Duration
+}
+
+// WithRateLimit permit to limit the rate and the concurrency of requests sent to Kibana
+// With WithMetrics, the wait time, the queue depth and the requests in flight are exposed as Prometheus metrics
+func WithRateLimit(limit RateLimit) Option {
+	return func(h *KibanaHandlerImpl) {
+		h.limiter = newLimiterTransport(limit)
+	}
+}
+
+// RateLimitStats return the metrics of rate limit
+// It return empty stats if rate limit is not enabled
+func (h *KibanaHandlerImpl) RateLimitStats() (stats RateLimitStats) {
+	if h.limiter == nil {
+		return stats
+	}
+
+	return RateLimitStats{
+		Requests:        atomic.LoadInt64(&h.limiter.requests),
+		InFlight:        atomic.LoadInt64(&h.limiter.inFlight),
+		Waiting:         atomic.LoadInt64(&h.limiter.waiting),
+		RateLimitWait:   time.Duration(atomic.LoadInt64(&h.limiter.rateLimitWait)),
+		ConcurrencyWait: time.Duration(atomic.LoadInt64(&h.limiter.concurrencyWait)),
+	}
+}
+
+// limiterTransport is the HTTP transport that apply the rate limit
+// The stats are recorded on the Prometheus collectors too when WithMetrics is used
+type limiterTransport struct {
+	next      http.RoundTripper
+	limiter   *rate.Limiter
+	semaphore chan struct{}
+	metrics   *Metrics
+
+	requests        int64
+	inFlight        int64
+	waiting         int64
+	rateLimitWait   int64
+	concurrencyWait int64
+}
+
+func newLimiterTransport(limit RateLimit) *limiterTransport {
+	t := &limiterTransport{}
+	if limit.RequestsPerSecond > 0 {
+		burst := limit.Burst
+		if burst < 1 {
+			burst = 1
+		}
+		t.limiter = rate.NewLimiter(rate.Limit(limit.RequestsPerSecond), burst)
+	}
+	if limit.MaxInFlight > 0 {
+		t.semaphore = make(chan struct{}, limit.MaxInFlight)
+	}
+
+	return t
+}
+
+func (t *limiterTransport) RoundTrip(req *http.Request) (resp *http.Response, err error) {
+	ctx := req.Context()
+
+	if t.limiter != nil {
+		start := time.Now()
+		done := t.wait()
+		err = t.limiter.Wait(ctx)
+		done()
+		if err != nil {
+			return nil, err
+		}
+		wait := time.Since(start)
+		atomic.AddInt64(&t.rateLimitWait, int64(wait))
+		t.metrics.observeThrottleWait("rate", wait)
+	}
+
+	if t.semaphore != nil {
+		start := time.Now()
+		done := t.wait()
+		select {
+		case t.semaphore <- struct{}{}:
+			done()
+		case <-ctx.Done():
+			done()
+			return nil, ctx.Err()
+		}
+		wait := time.Since(start)
+		atomic.AddInt64(&t.concurrencyWait, int64(wait))
+		t.metrics.observeThrottleWait("concurrency", wait)
+	}
+
+	atomic.AddInt64(&t.requests, 1)
+	atomic.AddInt64(&t.inFlight, 1)
+	t.metrics.observeInFlight(1)
+	release := t.release()
+
+	resp, err = t.next.RoundTrip(req)
+	if err != nil || resp.Body == nil {
+		release()
+		return resp, err
+	}
+
+	// The slot is released when the response is read
+	resp.Body = &releaseBody{ReadCloser: resp.Body, release: release}
+
+	return resp, nil
+}
+
+// wait record the request as waiting, and return the function to call when the wait is over
+func (t *limiterTransport) wait() func() {
+	atomic.AddInt64(&t.waiting, 1)
+	t.metrics.observeQueueDepth(1)
+
+	return func() {
+		atomic.AddInt64(&t.waiting, -1)
+		t.metrics.observeQueueDepth(-1)
+	}
+}
+
+// release return the function that free the slot of request, only one time
+func (t *limiterTransport) release() func() {
+	once := &sync.Once{}
+
+	return func() {
+		once.Do(func() {
+			atomic.AddInt64(&t.inFlight, -1)
+			t.metrics.observeInFlight(-1)
+			if t.semaphore != nil {
+				<-t.semaphore
+			}
+		})
+	}
+}
+
+// releaseBody free the slot of request when the response body is closed
+type releaseBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+
+	return err
+}
@@ -0,0 +1,66 @@
+package kbhandler
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func (t *KibanaHandlerTestSuite) TestRateLimit() {
+
+	var current, maxConcurrent int64
+	httpmock.RegisterResponder("GET", urlrole, func(req *http.Request) (*http.Response, error) {
+		n := atomic.AddInt64(&current, 1)
+		defer atomic.AddInt64(&current, -1)
+		for {
+			m := atomic.LoadInt64(&maxConcurrent)
+			if n <= m || atomic.CompareAndSwapInt64(&maxConcurrent, m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		return httpmock.NewStringResponse(200, `{"name": "test"}`), nil
+	})
+
+	// When concurrency is limited
+	handler := *t.kbHandler.(*KibanaHandlerImpl)
+	WithRateLimit(RateLimit{MaxInFlight: 2})(&handler)
+	handler.setupTransport()
+
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := handler.RoleGet("test"); err != nil {
+				t.Fail(err.Error())
+			}
+		}()
+	}
+	wg.Wait()
+	assert.LessOrEqual(t.T(), maxConcurrent, int64(2))
+	stats := handler.RateLimitStats()
+	assert.Equal(t.T(), int64(6), stats.Requests)
+	assert.Equal(t.T(), int64(0), stats.InFlight)
+	assert.Greater(t.T(), stats.ConcurrencyWait, time.Duration(0))
+
+	// When rate is limited
+	t.SetupTest()
+	httpmock.RegisterResponder("GET", urlrole, httpmock.NewStringResponder(200, `{"name": "test"}`))
+	handler = *t.kbHandler.(*KibanaHandlerImpl)
+	WithRateLimit(RateLimit{RequestsPerSecond: 100, Burst: 1})(&handler)
+	handler.setupTransport()
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if _, err := handler.RoleGet("test"); err != nil {
+			t.Fail(err.Error())
+		}
+	}
+	assert.GreaterOrEqual(t.T(), time.Since(start), 35*time.Millisecond)
+	assert.Greater(t.T(), handler.RateLimitStats().RateLimitWait, time.Duration(0))
+}
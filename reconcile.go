@@ -0,0 +1,170 @@
+package kbhandler
+
+import (
+	"github.com/disaster37/generic-objectmatcher/patch"
+	"github.com/disaster37/go-kibana-rest/v8/kbapi"
+	"github.com/pkg/errors"
+)
+
+// ReconcileAction is the action done by Reconcile
+type ReconcileAction string
+
+const (
+	// ReconcileCreated is used when object not exist and is created
+	ReconcileCreated ReconcileAction = "Created"
+
+	// ReconcileUpdated is used when object exist and is updated
+	ReconcileUpdated ReconcileAction = "Updated"
+
+	// ReconcileUnchanged is used when object already match the expected object
+	ReconcileUnchanged ReconcileAction = "Unchanged"
+)
+
+// ReconcileResult is the result of Reconcile
+type ReconcileResult[T any] struct {
+	// Action is the action done on Kibana
+	Action ReconcileAction
+
+	// Patch is the diff computed between actual and expected object, it can be used for auditing
+	Patch *patch.PatchResult
+
+	// Actual is the object read on Kibana before reconcile. It's nil when object is created
+	Actual *T
+}
+
+// Reconciler contain the functions used by Reconcile to handle one object
+type Reconciler[T any] struct {
+	Get    func() (object *T, err error)
+	Diff   func(actualObject, expectedObject, originalObject *T) (patchResult *patch.PatchResult, err error)
+	Create func(object *T) (err error)
+	Update func(object *T) (err error)
+}
+
+// Reconcile permit to get the current object, compute the diff with the expected object and create or update it only if needed
+// The original object is the last applied object, it can be nil
+func Reconcile[T any](reconciler Reconciler[T], expectedObject, originalObject *T) (result *ReconcileResult[T], err error) {
+	actual, err := reconciler.Get()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get actual object")
+	}
+
+	patchResult, err := reconciler.Diff(actual, expectedObject, originalObject)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to diff object")
+	}
+
+	result = &ReconcileResult[T]{
+		Patch:  patchResult,
+		Actual: actual,
+	}
+
+	if actual == nil {
+		if err = reconciler.Create(expectedObject); err != nil {
+			return nil, errors.Wrap(err, "Failed to create object")
+		}
+		result.Action = ReconcileCreated
+		return result, nil
+	}
+
+	if patchResult.IsEmpty() {
+		result.Action = ReconcileUnchanged
+		return result, nil
+	}
+
+	if err = reconciler.Update(expectedObject); err != nil {
+		return nil, errors.Wrap(err, "Failed to update object")
+	}
+	result.Action = ReconcileUpdated
+
+	return result, nil
+}
+
+// RoleReconciler return the reconciler for role
+func RoleReconciler(h KibanaHandler, name string) Reconciler[kbapi.KibanaRole] {
+	return Reconciler[kbapi.KibanaRole]{
+		Get:    func() (*kbapi.KibanaRole, error) { return h.RoleGet(name) },
+		Diff:   h.RoleDiff,
+		Create: h.RoleUpdate,
+		Update: h.RoleUpdate,
+	}
+}
+
+// UserSpaceReconciler return the reconciler for user space
+func UserSpaceReconciler(h KibanaHandler, name string) Reconciler[kbapi.KibanaSpace] {
+	return Reconciler[kbapi.KibanaSpace]{
+		Get:    func() (*kbapi.KibanaSpace, error) { return h.UserSpaceGet(name) },
+		Diff:   h.UserSpaceDiff,
+		Create: h.UserSpaceCreate,
+		Update: h.UserSpaceUpdate,
+	}
+}
+
+// LogstashPipelineReconciler return the reconciler for Logstash pipeline
+func LogstashPipelineReconciler(h KibanaHandler, name string) Reconciler[kbapi.LogstashPipeline] {
+	return Reconciler[kbapi.LogstashPipeline]{
+		Get:    func() (*kbapi.LogstashPipeline, error) { return h.LogstashPipelineGet(name) },
+		Diff:   h.LogstashPipelineDiff,
+		Create: h.LogstashPipelineUpdate,
+		Update: h.LogstashPipelineUpdate,
+	}
+}
+
+// SavedObjectReconciler return the reconciler for saved object on user space
+func SavedObjectReconciler(h KibanaHandler, objectType, id, userSpace string) Reconciler[SavedObject] {
+	return Reconciler[SavedObject]{
+		Get:    func() (*SavedObject, error) { return h.SavedObjectGet(objectType, id, userSpace) },
+		Diff:   h.SavedObjectDiff,
+		Create: func(o *SavedObject) error { return h.SavedObjectCreate(o, userSpace) },
+		Update: func(o *SavedObject) error { return h.SavedObjectUpdate(o, userSpace) },
+	}
+}
+
+// DataViewReconciler return the reconciler for data view on user space
+func DataViewReconciler(h KibanaHandler, id, userSpace string) Reconciler[DataView] {
+	return Reconciler[DataView]{
+		Get:    func() (*DataView, error) { return h.DataViewGet(id, userSpace) },
+		Diff:   h.DataViewDiff,
+		Create: func(o *DataView) error { return h.DataViewCreate(o, userSpace) },
+		Update: func(o *DataView) error { return h.DataViewUpdate(o, userSpace) },
+	}
+}
+
+// AlertingRuleReconciler return the reconciler for alerting rule on user space
+func AlertingRuleReconciler(h KibanaHandler, id, userSpace string) Reconciler[AlertingRule] {
+	return Reconciler[AlertingRule]{
+		Get:    func() (*AlertingRule, error) { return h.AlertingRuleGet(id, userSpace) },
+		Diff:   h.AlertingRuleDiff,
+		Create: func(o *AlertingRule) error { return h.AlertingRuleCreate(o, userSpace) },
+		Update: func(o *AlertingRule) error { return h.AlertingRuleUpdate(o, userSpace) },
+	}
+}
+
+// ConnectorReconciler return the reconciler for connector on user space
+func ConnectorReconciler(h KibanaHandler, id, userSpace string) Reconciler[Connector] {
+	return Reconciler[Connector]{
+		Get:    func() (*Connector, error) { return h.ConnectorGet(id, userSpace) },
+		Diff:   h.ConnectorDiff,
+		Create: func(o *Connector) error { return h.ConnectorCreate(o, userSpace) },
+		Update: func(o *Connector) error { return h.ConnectorUpdate(o, userSpace) },
+	}
+}
+
+// FleetAgentPolicyReconciler return the reconciler for Fleet agent policy
+func FleetAgentPolicyReconciler(h KibanaHandler, id string) Reconciler[FleetAgentPolicy] {
+	return Reconciler[FleetAgentPolicy]{
+		Get:    func() (*FleetAgentPolicy, error) { return h.FleetAgentPolicyGet(id) },
+		Diff:   h.FleetAgentPolicyDiff,
+		Create: h.FleetAgentPolicyCreate,
+		Update: h.FleetAgentPolicyUpdate,
+	}
+}
+
+// FleetPackagePolicyReconciler return the reconciler for Fleet package policy
+func FleetPackagePolicyReconciler(h KibanaHandler, id string) Reconciler[FleetPackagePolicy] {
+	return Reconciler[FleetPackagePolicy]{
+		Get:    func() (*FleetPackagePolicy, error) { return h.FleetPackagePolicyGet(id) },
+		Diff:   h.FleetPackagePolicyDiff,
+		Create: h.FleetPackagePolicyCreate,
+		Update: h.FleetPackagePolicyUpdate,
+	}
+}
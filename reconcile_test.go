@@ -0,0 +1,79 @@
+package kbhandler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/disaster37/go-kibana-rest/v8/kbapi"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func (t *KibanaHandlerTestSuite) TestReconcile() {
+
+	rawRole := `
+{
+	"name": "test",
+	"elasticsearch": {
+		"cluster": ["monitor"]
+	},
+	"kibana": [
+		{
+			"base": ["read"],
+			"spaces": ["default"]
+		}
+	]
+}
+	`
+	expected := &kbapi.KibanaRole{}
+	if err := json.Unmarshal([]byte(rawRole), expected); err != nil {
+		panic(err)
+	}
+
+	// When role not exist yet
+	httpmock.RegisterResponder("GET", urlrole, httpmock.NewStringResponder(404, `{}`))
+	httpmock.RegisterResponder("PUT", urlrole, func(req *http.Request) (*http.Response, error) {
+		httpmock.RegisterResponder("GET", urlrole, httpmock.NewStringResponder(200, rawRole))
+		return httpmock.NewStringResponse(204, ""), nil
+	})
+	result, err := Reconcile(RoleReconciler(t.kbHandler, "test"), expected, nil)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Equal(t.T(), ReconcileCreated, result.Action)
+	assert.Nil(t.T(), result.Actual)
+	assert.False(t.T(), result.Patch.IsEmpty())
+	assert.Equal(t.T(), 1, httpmock.GetCallCountInfo()["PUT "+urlrole])
+
+	// When role is the same
+	// go-kibana-rest reset the role name when create it
+	expected.Name = "test"
+	result, err = Reconcile(RoleReconciler(t.kbHandler, "test"), expected, expected)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Equal(t.T(), ReconcileUnchanged, result.Action)
+	assert.True(t.T(), result.Patch.IsEmpty())
+	assert.Equal(t.T(), 1, httpmock.GetCallCountInfo()["PUT "+urlrole])
+
+	// When role need to be updated
+	original := &kbapi.KibanaRole{}
+	if err := json.Unmarshal([]byte(rawRole), original); err != nil {
+		panic(err)
+	}
+	expected.Elasticsearch.Cluster = []string{"monitor", "manage_ilm"}
+	result, err = Reconcile(RoleReconciler(t.kbHandler, "test"), expected, original)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Equal(t.T(), ReconcileUpdated, result.Action)
+	assert.NotNil(t.T(), result.Actual)
+	assert.False(t.T(), result.Patch.IsEmpty())
+	assert.Equal(t.T(), 2, httpmock.GetCallCountInfo()["PUT "+urlrole])
+
+	// When error
+	httpmock.RegisterResponder("GET", urlrole, httpmock.NewErrorResponder(errors.New("fack error")))
+	_, err = Reconcile(RoleReconciler(t.kbHandler, "test"), expected, original)
+	assert.Error(t.T(), err)
+}
@@ -0,0 +1,452 @@
+package kbhandler
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/disaster37/generic-objectmatcher/patch"
+	jsonIterator "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+)
+
+// RenderFormat is the output format of RenderDiff
+type RenderFormat string
+
+const (
+	// RenderText is a unified text diff
+	RenderText RenderFormat = "text"
+
+	// RenderColor is a unified text diff colorized for terminal
+	RenderColor RenderFormat = "color"
+
+	// RenderMarkdown is a unified text diff on markdown code block, to use on PR comments
+	RenderMarkdown RenderFormat = "markdown"
+)
+
+const (
+	colorRed   = "\033[31m"
+	colorGreen = "\033[32m"
+	colorReset = "\033[0m"
+	maskValue  = `"******"`
+)
+
+// defaultSecretKeys are the keys masked by RenderDiff. The match is case insensitive and apply on all children
+var defaultSecretKeys = []string{"password", "secret", "token", "api_key", "apikey", "private_key", "credentials"}
+
+// RenderOptions permit to customize RenderDiff
+type RenderOptions struct {
+	// Format is the output format. Default to RenderText
+	Format RenderFormat
+
+	// SecretKeys are the extra keys where values are masked, on top of the default keys (password, secret, token, etc.)
+	SecretKeys []string
+}
+
+// diffLine is one changed field
+type diffLine struct {
+	path      string
+	old       *string
+	new       *string
+	multiOld  string
+	multiNew  string
+	maskedOld string
+	maskedNew string
+}
+
+// RenderDiff permit to render the result of Diff methods as field path based diff, readable by humans
+// It return empty string when there are no change. Secrets are always masked
+func RenderDiff(patchResult *patch.PatchResult, opts *RenderOptions) (diff string, err error) {
+	if patchResult == nil || patchResult.IsEmpty() {
+		return "", nil
+	}
+	if opts == nil {
+		opts = &RenderOptions{}
+	}
+
+	var current, expected any
+	// The diff methods set current to the expected object when object not exist yet
+	if len(patchResult.Current) > 0 && (patchResult.Original != nil || string(patchResult.Current) != string(patchResult.Modified)) {
+		if err = jsonIterator.ConfigCompatibleWithStandardLibrary.Unmarshal(patchResult.Current, &current); err != nil {
+			return "", errors.Wrap(err, "Failed to decode current object")
+		}
+
+		// The expected side is computed from current and patch, not from patched object,
+		// so the diff rules applied on current are applied on expected too
+		var base, changes any
+		if err = jsonIterator.ConfigCompatibleWithStandardLibrary.Unmarshal(patchResult.Current, &base); err != nil {
+			return "", errors.Wrap(err, "Failed to decode current object")
+		}
+		if err = jsonIterator.ConfigCompatibleWithStandardLibrary.Unmarshal(patchResult.Patch, &changes); err != nil {
+			return "", errors.Wrap(err, "Failed to decode patch")
+		}
+		expected = alignLogstashConfigs(current, mergePatch(base, changes))
+	} else {
+		patched, err := jsonIterator.ConfigCompatibleWithStandardLibrary.Marshal(patchResult.Patched)
+		if err != nil {
+			return "", errors.Wrap(err, "Failed to convert patched object to byte sequence")
+		}
+		if err = jsonIterator.ConfigCompatibleWithStandardLibrary.Unmarshal(patched, &expected); err != nil {
+			return "", errors.Wrap(err, "Failed to decode patched object")
+		}
+	}
+
+	secretKeys := make([]string, 0, len(defaultSecretKeys)+len(opts.SecretKeys))
+	for _, key := range append(defaultSecretKeys, opts.SecretKeys...) {
+		secretKeys = append(secretKeys, strings.ToLower(key))
+	}
+
+	currentFields := map[string]any{}
+	expectedFields := map[string]any{}
+	flatten("", current, false, secretKeys, currentFields)
+	flatten("", expected, false, secretKeys, expectedFields)
+
+	lines := diffFields(currentFields, expectedFields)
+	if len(lines) == 0 {
+		return "", nil
+	}
+
+	return renderLines(lines, opts.Format), nil
+}
+
+// mergePatch apply the JSON merge patch (RFC 7386) on target
+func mergePatch(target, changes any) any {
+	changesObject, ok := changes.(map[string]any)
+	if !ok {
+		return changes
+	}
+	targetObject, ok := target.(map[string]any)
+	if !ok {
+		targetObject = map[string]any{}
+	}
+	for key, value := range changesObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = mergePatch(targetObject[key], value)
+	}
+
+	return targetObject
+}
+
+// alignLogstashConfigs put the Logstash pipeline configs of expected on canonical form when the current config is on canonical form
+// The semantic diff of Logstash pipelines give the canonical current config and the expected config as written by its author
+func alignLogstashConfigs(current, expected any) any {
+	switch e := expected.(type) {
+	case map[string]any:
+		c, ok := current.(map[string]any)
+		if !ok {
+			return expected
+		}
+		for key, value := range e {
+			e[key] = alignLogstashConfigs(c[key], value)
+		}
+	case string:
+		c, ok := current.(string)
+		if !ok || c == e || !strings.Contains(c, "=>") && !strings.Contains(e, "=>") {
+			return expected
+		}
+		currentConfig, err := ParseLogstashConfig(c)
+		if err != nil || currentConfig.String() != c {
+			return expected
+		}
+		if expectedConfig, err := ParseLogstashConfig(e); err == nil {
+			return expectedConfig.String()
+		}
+	}
+
+	return expected
+}
+
+// flatten convert object as list of field path / leaf value
+func flatten(path string, value any, masked bool, secretKeys []string, fields map[string]any) {
+	switch v := value.(type) {
+	case nil:
+		if path != "" {
+			fields[path] = nil
+		}
+	case map[string]any:
+		if len(v) == 0 && path != "" {
+			fields[path] = v
+			return
+		}
+		for key, child := range v {
+			childPath := key
+			if path != "" {
+				childPath = path + "." + key
+			}
+			flatten(childPath, child, masked || isSecretKey(key, secretKeys), secretKeys, fields)
+		}
+	case []any:
+		if len(v) == 0 {
+			fields[path] = v
+			return
+		}
+		for i, child := range v {
+			flatten(fmt.Sprintf("%s[%d]", path, i), child, masked, secretKeys, fields)
+		}
+	default:
+		if masked {
+			fields[path] = secretValue{value: v}
+			return
+		}
+		// Logstash pipeline config can contain secrets as plugin settings
+		if text, ok := v.(string); ok && strings.Contains(text, "=>") {
+			if maskedText := maskLogstashSecrets(text, secretKeys); maskedText != text {
+				fields[path] = secretText{value: text, masked: maskedText}
+				return
+			}
+		}
+		fields[path] = v
+	}
+}
+
+// maskLogstashSecrets mask the values of Logstash settings and hash keys that match secret keys, like password => "secret"
+// The layout is kept, so the masked config has the same lines than config
+// When config is invalid, the values are masked line by line
+func maskLogstashSecrets(config string, secretKeys []string) string {
+	spans, err := logstashValueSpans(config)
+	if err != nil {
+		lines := strings.Split(config, "\n")
+		for i, line := range lines {
+			index := strings.Index(line, "=>")
+			if index < 0 {
+				continue
+			}
+			fields := strings.Fields(line[:index])
+			if len(fields) > 0 && isSecretKey(strings.Trim(fields[len(fields)-1], `"'`), secretKeys) {
+				lines[i] = line[:index] + "=> " + maskValue
+			}
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	input := []rune(config)
+	sb := &strings.Builder{}
+	last := 0
+	sort.Slice(spans, func(i, j int) bool {
+		return spans[i].start < spans[j].start
+	})
+	for _, span := range spans {
+		// The values inside masked value are already masked
+		if span.start < last || !isSecretKey(span.name, secretKeys) {
+			continue
+		}
+		sb.WriteString(string(input[last:span.start]))
+		sb.WriteString(maskValue)
+		sb.WriteString(strings.Repeat("\n", strings.Count(string(input[span.start:span.end]), "\n")))
+		last = span.end
+	}
+	sb.WriteString(string(input[last:]))
+
+	return sb.String()
+}
+
+// secretValue is the placeholder of masked value
+// It keep the value to detect change without display it
+type secretValue struct {
+	value any
+}
+
+// secretText is the text where some values are masked
+// It keep the text to detect change without display it
+type secretText struct {
+	value  string
+	masked string
+}
+
+// textValue return the text and its masked form if value is text
+func textValue(value any) (text, masked string, ok bool) {
+	switch v := value.(type) {
+	case string:
+		return v, v, true
+	case secretText:
+		return v.value, v.masked, true
+	default:
+		return "", "", false
+	}
+}
+
+// isSecretKey return true if key contain one of secret keys
+func isSecretKey(key string, secretKeys []string) bool {
+	key = strings.ToLower(key)
+	for _, secretKey := range secretKeys {
+		if strings.Contains(key, secretKey) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// diffFields compute the changed fields, sorted by path
+func diffFields(currentFields, expectedFields map[string]any) []diffLine {
+	paths := make([]string, 0, len(currentFields)+len(expectedFields))
+	for path := range currentFields {
+		paths = append(paths, path)
+	}
+	for path := range expectedFields {
+		if _, ok := currentFields[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	lines := make([]diffLine, 0, len(paths))
+	for _, path := range paths {
+		oldValue, hasOld := currentFields[path]
+		newValue, hasNew := expectedFields[path]
+		line := diffLine{path: path}
+
+		if hasOld && hasNew {
+			if reflect.DeepEqual(oldValue, newValue) {
+				continue
+			}
+			oldString, oldMasked, oldIsString := textValue(oldValue)
+			newString, newMasked, newIsString := textValue(newValue)
+			if oldIsString && newIsString && (strings.Contains(oldString, "\n") || strings.Contains(newString, "\n")) {
+				line.multiOld = oldString
+				line.multiNew = newString
+				line.maskedOld = oldMasked
+				line.maskedNew = newMasked
+				lines = append(lines, line)
+				continue
+			}
+		}
+		if hasOld {
+			value := formatValue(oldValue)
+			line.old = &value
+		}
+		if hasNew {
+			value := formatValue(newValue)
+			line.new = &value
+		}
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+// formatValue return the JSON representation of leaf value
+func formatValue(value any) string {
+	switch v := value.(type) {
+	case secretValue:
+		return maskValue
+	case secretText:
+		value = v.masked
+	}
+	b, err := jsonIterator.ConfigCompatibleWithStandardLibrary.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+
+	return string(b)
+}
+
+// renderLines write the diff on expected format
+func renderLines(lines []diffLine, format RenderFormat) string {
+	sb := &strings.Builder{}
+	if format == RenderMarkdown {
+		sb.WriteString("```diff\n")
+	}
+	writeLine(sb, format, 0, "--- actual")
+	writeLine(sb, format, 0, "+++ expected")
+
+	for _, line := range lines {
+		if line.old == nil && line.new == nil {
+			writeLine(sb, format, '~', fmt.Sprintf("%s:", line.path))
+			for _, l := range diffMaskedText(line.multiOld, line.multiNew, line.maskedOld, line.maskedNew) {
+				writeLine(sb, format, rune(l[0]), "    "+l[1:])
+			}
+			continue
+		}
+		if line.old != nil {
+			writeLine(sb, format, '-', fmt.Sprintf("%s: %s", line.path, *line.old))
+		}
+		if line.new != nil {
+			writeLine(sb, format, '+', fmt.Sprintf("%s: %s", line.path, *line.new))
+		}
+	}
+
+	if format == RenderMarkdown {
+		sb.WriteString("```\n")
+	}
+
+	return sb.String()
+}
+
+// writeLine write one line of diff, with color if needed
+func writeLine(sb *strings.Builder, format RenderFormat, op rune, text string) {
+	line := text
+	if op != 0 {
+		line = fmt.Sprintf("%c %s", op, text)
+	}
+
+	if format == RenderColor {
+		switch op {
+		case '-':
+			line = colorRed + line + colorReset
+		case '+':
+			line = colorGreen + line + colorReset
+		}
+	}
+
+	sb.WriteString(line)
+	sb.WriteString("\n")
+}
+
+// diffMaskedText compute line by line diff of multi-line text, based on longest common subsequence, but display the lines of masked text
+// Each returned line start with the operation (' ', '-' or '+')
+// So the lines where only a secret change are reported without display the secret
+func diffMaskedText(oldText, newText, oldMasked, newMasked string) []string {
+	a := strings.Split(oldText, "\n")
+	b := strings.Split(newText, "\n")
+	aDisplay := strings.Split(oldMasked, "\n")
+	bDisplay := strings.Split(newMasked, "\n")
+	if len(a) != len(aDisplay) || len(b) != len(bDisplay) {
+		a, b = aDisplay, bDisplay
+	}
+
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	lines := make([]string, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, " "+aDisplay[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, "-"+aDisplay[i])
+			i++
+		default:
+			lines = append(lines, "+"+bDisplay[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, "-"+aDisplay[i])
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, "+"+bDisplay[j])
+	}
+
+	return lines
+}
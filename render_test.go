@@ -0,0 +1,248 @@
+package kbhandler
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/disaster37/go-kibana-rest/v8/kbapi"
+	"github.com/stretchr/testify/assert"
+)
+
+func (t *KibanaHandlerTestSuite) TestRenderDiff() {
+
+	actual := &kbapi.LogstashPipeline{
+		ID:          "test",
+		Description: "test",
+		Pipeline:    "input {\n  stdin {}\n}\noutput {\n  stdout {}\n}",
+		Settings: map[string]interface{}{
+			"queue.type":       "memory",
+			"ssl_key_password": "old",
+		},
+	}
+	expected := &kbapi.LogstashPipeline{
+		ID:          "test",
+		Description: "my pipeline",
+		Pipeline:    "input {\n  stdin {}\n}\noutput {\n  elasticsearch {}\n}",
+		Settings: map[string]interface{}{
+			"queue.type":       "persisted",
+			"ssl_key_password": "new",
+			"pipeline.workers": 2,
+		},
+	}
+
+	// When no change
+	patchResult, err := t.kbHandler.LogstashPipelineDiff(actual, actual, nil)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	diff, err := RenderDiff(patchResult, nil)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Empty(t.T(), diff)
+
+	// When change
+	patchResult, err = t.kbHandler.LogstashPipelineDiff(actual, expected, nil)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	diff, err = RenderDiff(patchResult, nil)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Equal(t.T(), `--- actual
++++ expected
+- description: "test"
++ description: "my pipeline"
+~ pipeline:
+      input {
+        stdin {}
+      }
+      output {
+-       stdout {}
++       elasticsearch {}
+      }
++ settings.pipeline.workers: 2
+- settings.queue.type: "memory"
++ settings.queue.type: "persisted"
+- settings.ssl_key_password: "******"
++ settings.ssl_key_password: "******"
+`, diff)
+
+	// When markdown
+	diff, err = RenderDiff(patchResult, &RenderOptions{Format: RenderMarkdown})
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Contains(t.T(), diff, "```diff\n--- actual\n")
+	assert.NotContains(t.T(), diff, "new")
+
+	// When color
+	diff, err = RenderDiff(patchResult, &RenderOptions{Format: RenderColor})
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Contains(t.T(), diff, "\033[32m+ description: \"my pipeline\"\033[0m\n")
+
+	// When extra secret keys
+	diff, err = RenderDiff(patchResult, &RenderOptions{SecretKeys: []string{"queue"}})
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.NotContains(t.T(), diff, "persisted")
+
+	// When object not exist yet
+	patchResult, err = t.kbHandler.RoleDiff(nil, &kbapi.KibanaRole{
+		Name: "test",
+		Elasticsearch: &kbapi.KibanaRoleElasticsearch{
+			Cluster: []string{"monitor"},
+		},
+	}, nil)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	diff, err = RenderDiff(patchResult, nil)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Equal(t.T(), `--- actual
++++ expected
++ elasticsearch.cluster[0]: "monitor"
++ name: "test"
+`, diff)
+}
+
+func TestDiffMaskedText(t *testing.T) {
+	assert.Equal(t, []string{" a", "-b", "+c", " d"}, diffMaskedText("a\nb\nd", "a\nc\nd", "a\nb\nd", "a\nc\nd"))
+	assert.Equal(t, []string{"+a", " b"}, diffMaskedText("b", "a\nb", "b", "a\nb"))
+
+	// The secret change is reported with the masked line
+	assert.Equal(t, []string{" a", "-password => ******", "+password => ******"}, diffMaskedText("a\npassword => old", "a\npassword => new", "a\npassword => ******", "a\npassword => ******"))
+}
+
+func (t *KibanaHandlerTestSuite) TestRenderDiffWithRules() {
+
+	rawActual := `
+{
+	"name": "test",
+	"metadata": {
+		"version": 1
+	},
+	"transient_metadata": {
+		"enabled": true
+	},
+	"elasticsearch": {
+		"cluster": ["monitor"]
+	},
+	"kibana": [
+		{
+			"base": ["read"],
+			"spaces": ["team-b", "team-a"]
+		}
+	]
+}
+	`
+	rawExpected := `
+{
+	"name": "test",
+	"metadata": {
+		"version": 2
+	},
+	"elasticsearch": {
+		"cluster": ["monitor"]
+	},
+	"kibana": [
+		{
+			"base": ["read"],
+			"spaces": ["team-a", "team-b"]
+		}
+	]
+}
+	`
+	actual := &kbapi.KibanaRole{}
+	expected := &kbapi.KibanaRole{}
+	if err := json.Unmarshal([]byte(rawActual), actual); err != nil {
+		panic(err)
+	}
+	if err := json.Unmarshal([]byte(rawExpected), expected); err != nil {
+		panic(err)
+	}
+
+	patchResult, err := t.kbHandler.RoleDiff(actual, expected, nil)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	diff, err := RenderDiff(patchResult, nil)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Equal(t.T(), `--- actual
++++ expected
+- metadata.version: 1
++ metadata.version: 2
+`, diff)
+}
+
+func TestMergePatch(t *testing.T) {
+	target := map[string]any{"a": "b", "c": map[string]any{"d": "e", "f": "g"}}
+	changes := map[string]any{"a": "z", "c": map[string]any{"f": nil}, "h": []any{"i"}}
+
+	assert.Equal(t, map[string]any{"a": "z", "c": map[string]any{"d": "e"}, "h": []any{"i"}}, mergePatch(target, changes))
+}
+
+func (t *KibanaHandlerTestSuite) TestRenderDiffLogstashSecrets() {
+
+	actual := &kbapi.LogstashPipeline{
+		ID:       "test",
+		Pipeline: "input {\n  stdin {}\n}\noutput {\n  elasticsearch {\n    user => \"logstash\"\n    password => \"oldsecret\"\n  }\n}",
+	}
+	expected := &kbapi.LogstashPipeline{
+		ID:       "test",
+		Pipeline: "input {\n  stdin {}\n}\noutput {\n  elasticsearch {\n    user => \"logstash\"\n    password => \"newsecret\"\n  }\n}",
+	}
+
+	patchResult, err := t.kbHandler.LogstashPipelineDiff(actual, expected, nil)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	diff, err := RenderDiff(patchResult, nil)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Equal(t.T(), `--- actual
++++ expected
+~ pipeline:
+      input {
+        stdin {}
+      }
+      output {
+        elasticsearch {
+          user => "logstash"
+-         password => "******"
++         password => "******"
+        }
+      }
+`, diff)
+}
+
+func TestMaskLogstashSecrets(t *testing.T) {
+	secretKeys := []string{"password", "token"}
+
+	// When valid config
+	assert.Equal(t,
+		"output {\n  http {\n    headers => {\n      \"token\" => \"******\"\n    }\n    password => \"******\" # comment\n  }\n}",
+		maskLogstashSecrets("output {\n  http {\n    headers => {\n      \"token\" => \"abc\"\n    }\n    password => \"secret\" # comment\n  }\n}", secretKeys),
+	)
+
+	// When multi-line secret, the lines are kept
+	assert.Equal(t,
+		"output {\n  http {\n    password => \"******\"\n\n  }\n}",
+		maskLogstashSecrets("output {\n  http {\n    password => [\n\"a\"]\n  }\n}", secretKeys),
+	)
+
+	// When invalid config
+	assert.Equal(t,
+		"output {\n  http {\n    password => \"******\"\n",
+		maskLogstashSecrets("output {\n  http {\n    password => \"secret\"\n", secretKeys),
+	)
+}
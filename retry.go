@@ -0,0 +1,172 @@
+package kbhandler
+
+import (
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// RetryPolicy permit to retry the requests that failed because Kibana is temporary unavailable, like on rolling upgrade
+// The idempotent requests (GET, PUT, DELETE) are retried on retryable status codes and on network errors
+// The other requests (POST) are only retried when Kibana not process them (status 429 and 503, or connection refused), to not create objects twice
+type RetryPolicy struct {
+	// MaxAttempts is the max number of attempts, including the first one
+	MaxAttempts int
+
+	// InitialBackoff is the wait time before the first retry
+	InitialBackoff time.Duration
+
+	// MaxBackoff is the max wait time between attempts
+	MaxBackoff time.Duration
+
+	// Multiplier is the factor applied on backoff after each attempt
+	Multiplier float64
+
+	// Jitter is the random part of backoff, between 0 and 1
+	Jitter float64
+
+	// RetryableStatusCodes are the status codes that can be retried
+	RetryableStatusCodes []int
+
+	// RetryNonIdempotent permit to retry POST requests like idempotent requests
+	RetryNonIdempotent bool
+}
+
+// DefaultRetryPolicy is a retry policy that wait around 10 seconds before give up
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:          5,
+	InitialBackoff:       500 * time.Millisecond,
+	MaxBackoff:           5 * time.Second,
+	Multiplier:           2,
+	Jitter:               0.2,
+	RetryableStatusCodes: []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+}
+
+// WithRetryPolicy permit to retry all requests sent to Kibana with the policy
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(h *KibanaHandlerImpl) {
+		h.retryPolicy = &policy
+	}
+}
+
+// retryTransport is the HTTP transport that retry the requests with the policy
+type retryTransport struct {
+	next    http.RoundTripper
+	policy  RetryPolicy
+	metrics *Metrics
+
+	// handler is read to log with its current logger, so SetLogger apply on retries too
+	handler *KibanaHandlerImpl
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (resp *http.Response, err error) {
+	for attempt := 1; ; attempt++ {
+		// The request of caller must not be modified, so the body is sent again on a copy
+		attemptReq := req
+		if attempt > 1 && req.Body != nil && req.Body != http.NoBody {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq = req.Clone(req.Context())
+			attemptReq.Body = body
+		}
+
+		resp, err = t.next.RoundTrip(attemptReq)
+		if attempt >= t.policy.MaxAttempts || !t.canRetry(req, resp, err) {
+			return resp, err
+		}
+		if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+			return resp, err
+		}
+
+		t.metrics.observeRetry(req)
+		wait := t.backoff(attempt, resp)
+		if err != nil {
+			t.handler.log.Debug("Retry request after error", "method", req.Method, "path", req.URL.Path, "wait", wait.String(), "error", err.Error())
+		} else {
+			t.handler.log.Debug("Retry request after status code", "method", req.Method, "path", req.URL.Path, "wait", wait.String(), "status", resp.StatusCode)
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// canRetry return true if request can be retried
+func (t *retryTransport) canRetry(req *http.Request, resp *http.Response, err error) bool {
+	if req.Context().Err() != nil {
+		return false
+	}
+	idempotent := t.policy.RetryNonIdempotent || isIdempotent(req.Method)
+
+	if err != nil {
+		if idempotent {
+			return true
+		}
+		// The request is not sent when connection failed
+		opErr := &net.OpError{}
+		return errors.As(err, &opErr) && opErr.Op == "dial"
+	}
+
+	for _, statusCode := range t.policy.RetryableStatusCodes {
+		if resp.StatusCode != statusCode {
+			continue
+		}
+		// Kibana not process the request on these status codes
+		return idempotent || statusCode == http.StatusTooManyRequests || statusCode == http.StatusServiceUnavailable
+	}
+
+	return false
+}
+
+// backoff compute the wait time before next attempt
+// The Retry-After header is used when provided by Kibana
+func (t *retryTransport) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			wait := time.Duration(seconds) * time.Second
+			if t.policy.MaxBackoff > 0 && wait > t.policy.MaxBackoff {
+				return t.policy.MaxBackoff
+			}
+			return wait
+		}
+	}
+
+	multiplier := t.policy.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	wait := float64(t.policy.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if t.policy.MaxBackoff > 0 && wait > float64(t.policy.MaxBackoff) {
+		wait = float64(t.policy.MaxBackoff)
+	}
+	if t.policy.Jitter > 0 {
+		wait += wait * t.policy.Jitter * (rand.Float64()*2 - 1)
+	}
+
+	return time.Duration(wait)
+}
+
+// isIdempotent return true if the HTTP method can be sent many times without side effect
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,132 @@
+package kbhandler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/disaster37/go-kibana-rest/v8/kbapi"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func (t *KibanaHandlerTestSuite) TestRetryPolicy() {
+
+	handler := *t.kbHandler.(*KibanaHandlerImpl)
+	WithRetryPolicy(RetryPolicy{
+		MaxAttempts:          3,
+		InitialBackoff:       time.Millisecond,
+		MaxBackoff:           5 * time.Millisecond,
+		Multiplier:           2,
+		Jitter:               0.2,
+		RetryableStatusCodes: []int{http.StatusBadGateway, http.StatusServiceUnavailable},
+	})(&handler)
+	handler.setupTransport()
+
+	// failThen return the responder that fail n times before succeed
+	failThen := func(n int, fail httpmock.Responder, success httpmock.Responder) httpmock.Responder {
+		calls := 0
+		return func(req *http.Request) (*http.Response, error) {
+			calls++
+			if calls <= n {
+				return fail(req)
+			}
+			return success(req)
+		}
+	}
+
+	// When idempotent request succeed after retry
+	httpmock.RegisterResponder("GET", urlrole, failThen(2, httpmock.NewStringResponder(503, ""), httpmock.NewStringResponder(200, `{"name": "test"}`)))
+	role, err := handler.RoleGet("test")
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Equal(t.T(), "test", role.Name)
+	assert.Equal(t.T(), 3, httpmock.GetCallCountInfo()["GET "+urlrole])
+
+	// When max attempts is reached
+	httpmock.RegisterResponder("GET", urlrole, httpmock.NewStringResponder(503, ""))
+	httpmock.ZeroCallCounters()
+	_, err = handler.RoleGet("test")
+	assert.Error(t.T(), err)
+	assert.Equal(t.T(), 3, httpmock.GetCallCountInfo()["GET "+urlrole])
+
+	// When idempotent request failed on network error, the body is sent again
+	bodies := []string{}
+	httpmock.RegisterResponder("PUT", urlLogstashPipeline, failThen(1, httpmock.NewErrorResponder(errors.New("connection reset by peer")), func(req *http.Request) (*http.Response, error) {
+		b := make([]byte, req.ContentLength)
+		_, _ = req.Body.Read(b)
+		bodies = append(bodies, string(b))
+		return httpmock.NewStringResponse(204, ""), nil
+	}))
+	httpmock.RegisterResponder("GET", urlLogstashPipeline, httpmock.NewStringResponder(200, `{"id": "test"}`))
+	err = handler.LogstashPipelineUpdate(&kbapi.LogstashPipeline{ID: "test", Pipeline: "input { stdin {} }"})
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Equal(t.T(), []string{`{"pipeline":"input { stdin {} }"}`}, bodies)
+
+	// When non idempotent request failed with status that can be processed by Kibana, it's not retried
+	urlSpace := fmt.Sprintf("%s/api/spaces/space", baseURL)
+	httpmock.RegisterResponder("POST", urlSpace, httpmock.NewStringResponder(502, ""))
+	httpmock.ZeroCallCounters()
+	err = handler.UserSpaceCreate(&kbapi.KibanaSpace{ID: "test", Name: "test"})
+	assert.Error(t.T(), err)
+	assert.Equal(t.T(), 1, httpmock.GetCallCountInfo()["POST "+urlSpace])
+
+	// When non idempotent request failed on network error, it's not retried
+	httpmock.RegisterResponder("POST", urlSpace, httpmock.NewErrorResponder(errors.New("connection reset by peer")))
+	httpmock.ZeroCallCounters()
+	err = handler.UserSpaceCreate(&kbapi.KibanaSpace{ID: "test", Name: "test"})
+	assert.Error(t.T(), err)
+	assert.Equal(t.T(), 1, httpmock.GetCallCountInfo()["POST "+urlSpace])
+
+	// When non idempotent request is rejected by Kibana, it's retried
+	httpmock.RegisterResponder("POST", urlSpace, failThen(1, httpmock.NewStringResponder(503, ""), httpmock.NewStringResponder(200, `{"id": "test", "name": "test"}`)))
+	httpmock.ZeroCallCounters()
+	err = handler.UserSpaceCreate(&kbapi.KibanaSpace{ID: "test", Name: "test"})
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), 2, httpmock.GetCallCountInfo()["POST "+urlSpace])
+}
+
+func TestRetryTransportNotModifyRequest(t *testing.T) {
+	requests := []*http.Request{}
+	bodies := []string{}
+	transport := &retryTransport{
+		next: roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			requests = append(requests, req)
+			b, _ := io.ReadAll(req.Body)
+			bodies = append(bodies, string(b))
+			if len(requests) == 1 {
+				return nil, errors.New("connection reset by peer")
+			}
+			return httpmock.NewStringResponse(200, ""), nil
+		}),
+		policy:  RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond},
+		handler: &KibanaHandlerImpl{log: NewNopLogger()},
+	}
+
+	req, err := http.NewRequest(http.MethodPut, baseURL+"/api/logstash/pipeline/test", strings.NewReader("body"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := req.Body
+
+	_, err = transport.RoundTrip(req)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"body", "body"}, bodies)
+	assert.Same(t, req, requests[0])
+	assert.NotSame(t, req, requests[1])
+	assert.Equal(t, body, req.Body)
+}
+
+// roundTripperFunc is the HTTP transport implemented by function
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -1,6 +1,10 @@
 package kbhandler
 
 import (
+	"fmt"
+	"net/http"
+	"strings"
+
 	"github.com/disaster37/go-kibana-rest/v8/kbapi"
 	"github.com/disaster37/generic-objectmatcher/patch"
 	jsonIterator "github.com/json-iterator/go"
@@ -8,29 +12,105 @@ import (
 )
 
 // RoleUpdate permit to update or create role
+// When ownership is set, it's stamped on role metadata
+// When space check is enabled, the user spaces referenced by role are checked before
 func (h *KibanaHandlerImpl) RoleUpdate(role *kbapi.KibanaRole) (err error) {
-	h.log.Debugf("Update role %s", role.Name)
+	h.log.Debug("Update role", "scope", "role", "name", role.Name)
+	h, span := h.startSpan("RoleUpdate", "role", role.Name, "")
+	defer span.end(&err)
+
+	if h.roleSpaceCheck != RoleSpaceCheckDisabled {
+		missingSpaces, err := h.RoleCheckReferences(role)
+		if err != nil {
+			return err
+		}
+		if len(missingSpaces) > 0 {
+			if h.roleSpaceCheck == RoleSpaceCheckFail {
+				return errors.Wrapf(ErrMissingReference, "Role %s reference user spaces that not exist: %s", role.Name, strings.Join(missingSpaces, ", "))
+			}
+			h.log.Warn("Role reference user spaces that not exist", "scope", "role", "name", role.Name, "spaces", strings.Join(missingSpaces, ", "))
+		}
+	}
+
+	if h.ownership != nil {
+		if err = h.checkRoleOwner(role.Name); err != nil {
+			return err
+		}
+		role = h.ownership.stampRole(role)
+	}
+
+	if h.isDryRun() {
+		body := *role
+		body.Name = ""
+		return h.record(http.MethodPut, fmt.Sprintf("/api/security/role/%s", role.Name), &body)
+	}
 
 	_, err = h.client.KibanaRoleManagement.CreateOrUpdate(role)
-	return err
+	return wrapError(err)
 }
 
 // RoleDelete permit to delete role
 func (h *KibanaHandlerImpl) RoleDelete(name string) (err error) {
-	h.log.Debugf("Delete role %s", name)
+	h.log.Debug("Delete role", "scope", "role", "name", name)
+	h, span := h.startSpan("RoleDelete", "role", name, "")
+	defer span.end(&err)
 
-	return h.client.KibanaRoleManagement.Delete(name)
+	if h.ownership != nil {
+		if err = h.checkRoleOwner(name); err != nil {
+			return err
+		}
+	}
+
+	if h.isDryRun() {
+		return h.record(http.MethodDelete, fmt.Sprintf("/api/security/role/%s", name), nil)
+	}
+
+	return wrapError(h.client.KibanaRoleManagement.Delete(name))
 }
 
 // RoleGet permit to get a role
+// It return nil if role not exist
 func (h *KibanaHandlerImpl) RoleGet(name string) (role *kbapi.KibanaRole, err error) {
-	h.log.Debugf("Role name: %s", name)
+	h.log.Debug("Get role", "scope", "role", "name", name)
+	h, span := h.startSpan("RoleGet", "role", name, "")
+	defer span.end(&err)
+
+	role, err = h.client.KibanaRoleManagement.Get(name)
+	return role, wrapError(err)
+}
+
+// RoleList permit to list roles
+// Reserved roles are the roles with metadata _reserved
+func (h *KibanaHandlerImpl) RoleList(opts *ListOptions) (roles kbapi.KibanaRoles, err error) {
+	h.log.Debug("List roles", "scope", "role")
+	h, span := h.startSpan("RoleList", "role", "", "")
+	defer span.end(&err)
 
-	return h.client.KibanaRoleManagement.Get(name)
+	allRoles, err := h.client.KibanaRoleManagement.List()
+	if err != nil {
+		return nil, wrapError(err)
+	}
+
+	roles = make(kbapi.KibanaRoles, 0, len(allRoles))
+	for _, role := range allRoles {
+		reserved, _ := role.Metadata["_reserved"].(bool)
+		if opts.matchName(role.Name) && opts.matchReserved(reserved) && opts.matchLabels(role.Metadata) {
+			roles = append(roles, role)
+		}
+	}
+
+	return roles, nil
 }
 
 // RoleDiff permit to diff role
+// The diff rules of roles are applied before compute the diff
 func (h *KibanaHandlerImpl) RoleDiff(actualObject, expectedObject, originalObject *kbapi.KibanaRole) (patchResult *patch.PatchResult, err error) {
+	defer h.observeDiff("role", actualObject == nil, &patchResult)
+
+	if h.ownership != nil {
+		expectedObject = h.ownership.stampRole(expectedObject)
+	}
+
 	// If not yet exist
 	if actualObject == nil {
 		expected, err := jsonIterator.ConfigCompatibleWithStandardLibrary.Marshal(expectedObject)
@@ -47,5 +127,44 @@ func (h *KibanaHandlerImpl) RoleDiff(actualObject, expectedObject, originalObjec
 		}, nil
 	}
 
-	return patch.DefaultPatchMaker.Calculate(actualObject, expectedObject, originalObject)
+	return h.calculate(DiffKindRole, actualObject, expectedObject, originalObject)
+}
+
+// checkRoleOwner return an error if role is owned by another manager
+func (h *KibanaHandlerImpl) checkRoleOwner(name string) (err error) {
+	current, err := h.RoleGet(name)
+	if err != nil {
+		return errors.Wrapf(err, "Error when get role %s", name)
+	}
+
+	return h.checkOwner("role", name, h.ownership.roleOwner(current))
+}
+
+// RoleCheckReferences permit to get the user spaces referenced by role kibana privileges that not exist
+// The role grant nothing on missing user spaces
+func (h *KibanaHandlerImpl) RoleCheckReferences(role *kbapi.KibanaRole) (missingSpaces []string, err error) {
+	h.log.Debug("Check references of role", "scope", "role", "name", role.Name)
+	h, span := h.startSpan("RoleCheckReferences", "role", role.Name, "")
+	defer span.end(&err)
+
+	missingSpaces = []string{}
+	checked := map[string]bool{}
+	for _, kibana := range role.Kibana {
+		for _, space := range kibana.Spaces {
+			if space == "*" || checked[space] {
+				continue
+			}
+			checked[space] = true
+
+			userSpace, err := h.UserSpaceGet(space)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Error when get user space %s", space)
+			}
+			if userSpace == nil {
+				missingSpaces = append(missingSpaces, space)
+			}
+		}
+	}
+
+	return missingSpaces, nil
 }
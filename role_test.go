@@ -1,6 +1,7 @@
 package kbhandler
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,6 +10,8 @@ import (
 	"github.com/disaster37/go-kibana-rest/v8/kbapi"
 	"github.com/google/go-cmp/cmp"
 	"github.com/jarcoal/httpmock"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -514,3 +517,234 @@ func (t *KibanaHandlerTestSuite) TestRoleDiff() {
 	assert.Equal(t.T(), actual, diff.Patched)
 
 }
+
+func (t *KibanaHandlerTestSuite) TestRoleList() {
+
+	url := fmt.Sprintf("%s/api/security/role", baseURL)
+
+	rawRoles := `
+[
+	{
+		"name": "kibana_admin",
+		"metadata": {
+			"_reserved": true
+		},
+		"kibana": [
+			{
+				"base": ["all"],
+				"spaces": ["*"]
+			}
+		]
+	},
+	{
+		"name": "team-a-read",
+		"metadata": {
+			"managed-by": "kb-handler"
+		},
+		"kibana": [
+			{
+				"base": ["read"],
+				"spaces": ["team-a"]
+			}
+		]
+	},
+	{
+		"name": "team-b-read",
+		"kibana": [
+			{
+				"base": ["read"],
+				"spaces": ["team-b"]
+			}
+		]
+	}
+]
+	`
+
+	httpmock.RegisterResponder("GET", url, httpmock.NewStringResponder(200, rawRoles))
+
+	// Without filter
+	roles, err := t.kbHandler.RoleList(nil)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Len(t.T(), roles, 3)
+
+	// With name prefix
+	roles, err = t.kbHandler.RoleList(&ListOptions{NamePrefix: "team-"})
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Len(t.T(), roles, 2)
+
+	// With reserved
+	reserved := false
+	roles, err = t.kbHandler.RoleList(&ListOptions{Reserved: &reserved})
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Len(t.T(), roles, 2)
+	reserved = true
+	roles, err = t.kbHandler.RoleList(&ListOptions{Reserved: &reserved})
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Len(t.T(), roles, 1)
+	assert.Equal(t.T(), "kibana_admin", roles[0].Name)
+
+	// With labels
+	roles, err = t.kbHandler.RoleList(&ListOptions{Labels: map[string]string{"managed-by": "kb-handler"}})
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Len(t.T(), roles, 1)
+	assert.Equal(t.T(), "team-a-read", roles[0].Name)
+
+	// When error
+	httpmock.RegisterResponder("GET", url, httpmock.NewErrorResponder(errors.New("fack error")))
+	_, err = t.kbHandler.RoleList(nil)
+	assert.Error(t.T(), err)
+}
+
+func (t *KibanaHandlerTestSuite) TestRoleValidate() {
+
+	httpmock.RegisterResponder("GET", fmt.Sprintf("%s/api/features", baseURL), httpmock.NewStringResponder(200, `[{"id": "discover", "name": "Discover"}, {"id": "dashboard", "name": "Dashboard"}]`))
+	httpmock.RegisterResponder("GET", fmt.Sprintf("%s/api/security/privileges", baseURL), httpmock.NewStringResponder(200, `
+{
+	"global": ["all", "read"],
+	"space": ["all", "read"],
+	"features": {
+		"discover": ["all", "read", "minimal_all", "minimal_read", "url_create"],
+		"dashboard": ["all", "read"]
+	}
+}
+	`))
+	httpmock.RegisterResponder("GET", fmt.Sprintf("%s/api/spaces/space", baseURL), httpmock.NewStringResponder(200, `[{"id": "default", "name": "Default"}, {"id": "marketing", "name": "Marketing"}]`))
+
+	// When role is valid
+	role := &kbapi.KibanaRole{
+		Name: "test",
+		Elasticsearch: &kbapi.KibanaRoleElasticsearch{
+			Indices: []kbapi.KibanaRoleElasticsearchIndice{
+				{
+					Names:      []string{"logs-*"},
+					Privileges: []string{"read", "view_index_metadata"},
+				},
+			},
+		},
+		Kibana: []kbapi.KibanaRoleKibana{
+			{
+				Base:   []string{"read"},
+				Spaces: []string{"*"},
+			},
+			{
+				Feature: map[string][]string{
+					"discover": {"minimal_read", "url_create"},
+				},
+				Spaces: []string{"marketing"},
+			},
+		},
+	}
+	err := t.kbHandler.RoleValidate(role)
+	assert.NoError(t.T(), err)
+
+	// When role is invalid
+	role.Elasticsearch.Indices[0].Privileges = []string{"read", "reed"}
+	role.Kibana = []kbapi.KibanaRoleKibana{
+		{
+			Base: []string{"read"},
+			Feature: map[string][]string{
+				"discovr":   {"all"},
+				"dashboard": {"read", "write"},
+			},
+			Spaces: []string{"default", "sales"},
+		},
+	}
+	err = t.kbHandler.RoleValidate(role)
+	validationErr := &RoleValidationError{}
+	assert.True(t.T(), errors.As(err, &validationErr))
+	paths := make([]string, 0, len(validationErr.Errors))
+	for _, e := range validationErr.Errors {
+		paths = append(paths, e.(*FieldError).Path)
+	}
+	assert.Equal(t.T(), []string{
+		"/elasticsearch/indices/0/privileges/1",
+		"/kibana/0/spaces/1",
+		"/kibana/0",
+		"/kibana/0/feature/dashboard/1",
+		"/kibana/0/feature/discovr",
+	}, paths)
+
+	// When unknown index privileges are only warned
+	log, hook := test.NewNullLogger()
+	handler := *t.kbHandler.(*KibanaHandlerImpl)
+	handler.SetLogger(logrus.NewEntry(log))
+	WithRoleIndexPrivilegeCheck(RoleIndexPrivilegeCheckWarn)(&handler)
+	role.Kibana = nil
+	err = handler.RoleValidate(role)
+	assert.NoError(t.T(), err)
+	if assert.NotNil(t.T(), hook.LastEntry()) {
+		assert.Equal(t.T(), logrus.WarnLevel, hook.LastEntry().Level)
+		assert.Equal(t.T(), "reed", hook.LastEntry().Data["privilege"])
+		assert.Equal(t.T(), "/elasticsearch/indices/0/privileges/1", hook.LastEntry().Data["path"])
+	}
+
+	// The features and privileges are cached, and shared by the handlers created with context
+	_ = t.kbHandler.WithContext(context.Background()).RoleValidate(role)
+	assert.Equal(t.T(), 1, httpmock.GetCallCountInfo()[fmt.Sprintf("GET %s/api/features", baseURL)])
+	assert.Equal(t.T(), 1, httpmock.GetCallCountInfo()[fmt.Sprintf("GET %s/api/security/privileges", baseURL)])
+	assert.Equal(t.T(), 2, httpmock.GetCallCountInfo()[fmt.Sprintf("GET %s/api/spaces/space", baseURL)])
+}
+
+func (t *KibanaHandlerTestSuite) TestRoleCheckReferences() {
+
+	httpmock.RegisterResponder("GET", fmt.Sprintf("%s/api/spaces/space/marketing", baseURL), httpmock.NewStringResponder(200, `{"id": "marketing", "name": "Marketing"}`))
+	httpmock.RegisterResponder("GET", fmt.Sprintf("%s/api/spaces/space/sales", baseURL), httpmock.NewStringResponder(404, `{}`))
+	httpmock.RegisterResponder("PUT", urlrole, httpmock.NewStringResponder(204, ""))
+	httpmock.RegisterResponder("GET", urlrole, httpmock.NewStringResponder(200, `{"name": "test"}`))
+
+	role := &kbapi.KibanaRole{
+		Name: "test",
+		Kibana: []kbapi.KibanaRoleKibana{
+			{
+				Base:   []string{"read"},
+				Spaces: []string{"marketing", "sales"},
+			},
+			{
+				Base:   []string{"all"},
+				Spaces: []string{"*"},
+			},
+			{
+				Base:   []string{"all"},
+				Spaces: []string{"sales"},
+			},
+		},
+	}
+
+	// When some user spaces not exist
+	missingSpaces, err := t.kbHandler.RoleCheckReferences(role)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Equal(t.T(), []string{"sales"}, missingSpaces)
+	assert.Equal(t.T(), 1, httpmock.GetCallCountInfo()[fmt.Sprintf("GET %s/api/spaces/space/sales", baseURL)])
+
+	// When check fail on update
+	handler := *t.kbHandler.(*KibanaHandlerImpl)
+	WithRoleSpaceCheck(RoleSpaceCheckFail)(&handler)
+	err = handler.RoleUpdate(role)
+	assert.ErrorIs(t.T(), err, ErrMissingReference)
+	assert.NotErrorIs(t.T(), err, ErrNotFound)
+	assert.ErrorContains(t.T(), err, "sales")
+	assert.Equal(t.T(), 0, httpmock.GetCallCountInfo()["PUT "+urlrole])
+
+	// When check warn on update
+	WithRoleSpaceCheck(RoleSpaceCheckWarn)(&handler)
+	err = handler.RoleUpdate(role)
+	assert.NoError(t.T(), err)
+	assert.Equal(t.T(), 1, httpmock.GetCallCountInfo()["PUT "+urlrole])
+
+	// When error
+	httpmock.RegisterResponder("GET", fmt.Sprintf("%s/api/spaces/space/sales", baseURL), httpmock.NewErrorResponder(errors.New("fack error")))
+	_, err = t.kbHandler.RoleCheckReferences(role)
+	assert.Error(t.T(), err)
+}
@@ -0,0 +1,267 @@
+package kbhandler
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/disaster37/go-kibana-rest/v8/kbapi"
+	"github.com/pkg/errors"
+)
+
+// esIndexPrivileges are the index privileges known by Elasticsearch 8
+// Elasticsearch add new index privileges on minor releases, use WithRoleIndexPrivilegeCheck to only warn about the privileges not on this list
+var esIndexPrivileges = map[string]bool{
+	"all":                                true,
+	"auto_configure":                     true,
+	"create":                             true,
+	"create_doc":                         true,
+	"create_index":                       true,
+	"cross_cluster_replication":          true,
+	"cross_cluster_replication_internal": true,
+	"delete":                             true,
+	"delete_index":                       true,
+	"index":                              true,
+	"maintenance":                        true,
+	"manage":                             true,
+	"manage_data_stream_lifecycle":       true,
+	"manage_follow_index":                true,
+	"manage_ilm":                         true,
+	"manage_leader_index":                true,
+	"monitor":                            true,
+	"none":                               true,
+	"read":                               true,
+	"read_cross_cluster":                 true,
+	"view_index_metadata":                true,
+	"write":                              true,
+}
+
+// RoleSpaceCheck is the behavior of RoleUpdate when role reference user spaces that not exist
+type RoleSpaceCheck string
+
+const (
+	// RoleSpaceCheckDisabled not check the user spaces referenced by role
+	RoleSpaceCheckDisabled RoleSpaceCheck = ""
+
+	// RoleSpaceCheckWarn log a warning when user spaces referenced by role not exist
+	RoleSpaceCheckWarn RoleSpaceCheck = "warn"
+
+	// RoleSpaceCheckFail return an error that match ErrMissingReference when user spaces referenced by role not exist
+	RoleSpaceCheckFail RoleSpaceCheck = "fail"
+)
+
+// WithRoleSpaceCheck permit to check the user spaces referenced by role on RoleUpdate
+func WithRoleSpaceCheck(check RoleSpaceCheck) Option {
+	return func(h *KibanaHandlerImpl) {
+		h.roleSpaceCheck = check
+	}
+}
+
+// RoleIndexPrivilegeCheck is the behavior of RoleValidate when role use index privileges not known by handler
+type RoleIndexPrivilegeCheck string
+
+const (
+	// RoleIndexPrivilegeCheckFail report a FieldError for each unknown index privilege
+	RoleIndexPrivilegeCheckFail RoleIndexPrivilegeCheck = ""
+
+	// RoleIndexPrivilegeCheckWarn log a warning for each unknown index privilege, to use with Elasticsearch newer than the handler
+	RoleIndexPrivilegeCheckWarn RoleIndexPrivilegeCheck = "warn"
+)
+
+// WithRoleIndexPrivilegeCheck permit to choose the behavior of RoleValidate on unknown index privileges
+func WithRoleIndexPrivilegeCheck(check RoleIndexPrivilegeCheck) Option {
+	return func(h *KibanaHandlerImpl) {
+		h.roleIndexPrivilegeCheck = check
+	}
+}
+
+// FieldError is a problem found on object field. The path is a JSON pointer
+type FieldError struct {
+	Path    string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// RoleValidationError is returned by RoleValidate with all the problems found on role
+// Each problem is a FieldError
+type RoleValidationError struct {
+	Name   string
+	Errors []error
+}
+
+func (e *RoleValidationError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		messages = append(messages, err.Error())
+	}
+
+	return fmt.Sprintf("Role %s is invalid: %s", e.Name, strings.Join(messages, "; "))
+}
+
+func (e *RoleValidationError) Unwrap() []error {
+	return e.Errors
+}
+
+// kibanaFeature is a feature returned by /api/features
+type kibanaFeature struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// kibanaPrivileges is the privileges returned by /api/security/privileges
+type kibanaPrivileges struct {
+	Global   []string            `json:"global"`
+	Space    []string            `json:"space"`
+	Features map[string][]string `json:"features"`
+}
+
+// privilegeRegistry is the features and privileges known by Kibana
+// It's read only one time, because it change only when Kibana is upgraded
+type privilegeRegistry struct {
+	mu       sync.Mutex
+	loaded   bool
+	features map[string]bool
+	global   map[string]bool
+	space    map[string]bool
+	feature  map[string]map[string]bool
+}
+
+// load read the registry from Kibana if not yet done
+func (r *privilegeRegistry) load(h *KibanaHandlerImpl) (err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.loaded {
+		return nil
+	}
+
+	features := []kibanaFeature{}
+	if err = h.doRequest(http.MethodGet, "/api/features", nil, &features); err != nil {
+		return errors.Wrap(err, "Error when get Kibana features")
+	}
+	privileges := &kibanaPrivileges{}
+	if err = h.doRequest(http.MethodGet, "/api/security/privileges", nil, privileges); err != nil {
+		return errors.Wrap(err, "Error when get Kibana privileges")
+	}
+
+	r.features = make(map[string]bool, len(features))
+	for _, feature := range features {
+		r.features[feature.ID] = true
+	}
+	r.global = toSet(privileges.Global)
+	r.space = toSet(privileges.Space)
+	r.feature = make(map[string]map[string]bool, len(privileges.Features))
+	for feature, featurePrivileges := range privileges.Features {
+		r.feature[feature] = toSet(featurePrivileges)
+	}
+	r.loaded = true
+
+	return nil
+}
+
+// RoleValidate permit to check the role privileges against the features, the privileges and the user spaces known by Kibana
+// The features and privileges are cached, the user spaces are read on each call
+// The index privileges are not known by Kibana, they are checked against the privileges of Elasticsearch 8
+// It return RoleValidationError with all problems found
+func (h *KibanaHandlerImpl) RoleValidate(role *kbapi.KibanaRole) (err error) {
+	h.log.Debug("Validate role", "scope", "role", "name", role.Name)
+	h, span := h.startSpan("RoleValidate", "role", role.Name, "")
+	defer span.end(&err)
+
+	if err = h.registry.load(h); err != nil {
+		return err
+	}
+
+	validationError := &RoleValidationError{Name: role.Name}
+	addError := func(path, format string, args ...any) {
+		validationError.Errors = append(validationError.Errors, &FieldError{
+			Path:    path,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	if role.Elasticsearch != nil {
+		for i, index := range role.Elasticsearch.Indices {
+			for j, privilege := range index.Privileges {
+				if esIndexPrivileges[privilege] {
+					continue
+				}
+				path := fmt.Sprintf("/elasticsearch/indices/%d/privileges/%d", i, j)
+				if h.roleIndexPrivilegeCheck == RoleIndexPrivilegeCheckWarn {
+					h.log.Warn("Unknown index privilege, it can be a privilege added by a newer Elasticsearch", "scope", "role", "name", role.Name, "path", path, "privilege", privilege)
+					continue
+				}
+				addError(path, "unknown index privilege %s", privilege)
+			}
+		}
+	}
+
+	if len(role.Kibana) > 0 {
+		userSpaces, err := h.UserSpaceList(nil)
+		if err != nil {
+			return errors.Wrap(err, "Error when list user spaces")
+		}
+		knownSpaces := make(map[string]bool, len(userSpaces))
+		for _, userSpace := range userSpaces {
+			knownSpaces[userSpace.ID] = true
+		}
+
+		for i, kibana := range role.Kibana {
+			path := fmt.Sprintf("/kibana/%d", i)
+			global := false
+			for j, space := range kibana.Spaces {
+				switch {
+				case space == "*":
+					global = true
+					if len(kibana.Spaces) > 1 {
+						addError(fmt.Sprintf("%s/spaces/%d", path, j), "* can't be used with other spaces")
+					}
+				case !knownSpaces[space]:
+					addError(fmt.Sprintf("%s/spaces/%d", path, j), "unknown space %s", space)
+				}
+			}
+
+			if len(kibana.Base) > 0 && len(kibana.Feature) > 0 {
+				addError(path, "base and feature privileges can't be used together")
+			}
+
+			basePrivileges := h.registry.space
+			if global {
+				basePrivileges = h.registry.global
+			}
+			for j, privilege := range kibana.Base {
+				if !basePrivileges[privilege] {
+					addError(fmt.Sprintf("%s/base/%d", path, j), "unknown base privilege %s", privilege)
+				}
+			}
+
+			features := make([]string, 0, len(kibana.Feature))
+			for feature := range kibana.Feature {
+				features = append(features, feature)
+			}
+			sort.Strings(features)
+			for _, feature := range features {
+				if !h.registry.features[feature] {
+					addError(fmt.Sprintf("%s/feature/%s", path, feature), "unknown feature %s", feature)
+					continue
+				}
+				for j, privilege := range kibana.Feature[feature] {
+					if !h.registry.feature[feature][privilege] {
+						addError(fmt.Sprintf("%s/feature/%s/%d", path, feature, j), "unknown privilege %s for feature %s", privilege, feature)
+					}
+				}
+			}
+		}
+	}
+
+	if len(validationError.Errors) > 0 {
+		return validationError
+	}
+
+	return nil
+}
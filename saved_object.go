@@ -0,0 +1,129 @@
+package kbhandler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/disaster37/generic-objectmatcher/patch"
+	jsonIterator "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+)
+
+// SavedObject is the Kibana saved object (visualization, search, lens, etc.)
+// The version, the namespaces and the migration version returned by Kibana are not decoded, so only the attributes and the references are diffed
+type SavedObject struct {
+	ID         string                 `json:"id,omitempty"`
+	Type       string                 `json:"type,omitempty"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+	References []SavedObjectReference `json:"references,omitempty"`
+}
+
+// SavedObjectReference is a reference to another saved object
+type SavedObjectReference struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Name string `json:"name"`
+}
+
+// SavedObjectCreate permit to create saved object on user space
+func (h *KibanaHandlerImpl) SavedObjectCreate(savedObject *SavedObject, userSpace string) (err error) {
+	h.log.Debug("Create saved object", "scope", "saved_object", "type", savedObject.Type, "name", savedObject.ID, "space", userSpace)
+	h, span := h.startSpan("SavedObjectCreate", "saved_object", savedObject.ID, userSpace)
+	defer span.end(&err)
+
+	if h.isDryRun() {
+		return h.record(http.MethodPost, userSpacePath(userSpace, fmt.Sprintf("/api/saved_objects/%s/%s?overwrite=false", savedObject.Type, savedObject.ID)), savedObjectPayload(savedObject))
+	}
+
+	_, err = h.client.KibanaSavedObject.Create(savedObjectPayload(savedObject), savedObject.Type, savedObject.ID, false, userSpace)
+	return wrapError(err)
+}
+
+// SavedObjectUpdate permit to update saved object on user space
+func (h *KibanaHandlerImpl) SavedObjectUpdate(savedObject *SavedObject, userSpace string) (err error) {
+	h.log.Debug("Update saved object", "scope", "saved_object", "type", savedObject.Type, "name", savedObject.ID, "space", userSpace)
+	h, span := h.startSpan("SavedObjectUpdate", "saved_object", savedObject.ID, userSpace)
+	defer span.end(&err)
+
+	if h.isDryRun() {
+		return h.record(http.MethodPut, userSpacePath(userSpace, fmt.Sprintf("/api/saved_objects/%s/%s", savedObject.Type, savedObject.ID)), savedObjectPayload(savedObject))
+	}
+
+	_, err = h.client.KibanaSavedObject.Update(savedObjectPayload(savedObject), savedObject.Type, savedObject.ID, userSpace)
+	return wrapError(err)
+}
+
+// SavedObjectDelete permit to delete saved object on user space
+func (h *KibanaHandlerImpl) SavedObjectDelete(objectType, id, userSpace string) (err error) {
+	h.log.Debug("Delete saved object", "scope", "saved_object", "type", objectType, "name", id, "space", userSpace)
+	h, span := h.startSpan("SavedObjectDelete", "saved_object", id, userSpace)
+	defer span.end(&err)
+
+	if h.isDryRun() {
+		return h.record(http.MethodDelete, userSpacePath(userSpace, fmt.Sprintf("/api/saved_objects/%s/%s", objectType, id)), nil)
+	}
+
+	return wrapError(h.client.KibanaSavedObject.Delete(objectType, id, userSpace))
+}
+
+// SavedObjectGet permit to get saved object on user space
+// It return nil if saved object not exist
+func (h *KibanaHandlerImpl) SavedObjectGet(objectType, id, userSpace string) (savedObject *SavedObject, err error) {
+	h.log.Debug("Get saved object", "scope", "saved_object", "type", objectType, "name", id, "space", userSpace)
+	h, span := h.startSpan("SavedObjectGet", "saved_object", id, userSpace)
+	defer span.end(&err)
+
+	data, err := h.client.KibanaSavedObject.Get(objectType, id, userSpace)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	if data == nil {
+		return nil, nil
+	}
+
+	b, err := jsonIterator.ConfigCompatibleWithStandardLibrary.Marshal(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to convert saved object to byte sequence")
+	}
+	savedObject = &SavedObject{}
+	if err = jsonIterator.ConfigCompatibleWithStandardLibrary.Unmarshal(b, savedObject); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode saved object")
+	}
+
+	return savedObject, nil
+}
+
+// SavedObjectDiff permit to diff saved object
+func (h *KibanaHandlerImpl) SavedObjectDiff(actualObject, expectedObject, originalObject *SavedObject) (patchResult *patch.PatchResult, err error) {
+	defer h.observeDiff("saved_object", actualObject == nil, &patchResult)
+
+	// If not yet exist
+	if actualObject == nil {
+		expected, err := jsonIterator.ConfigCompatibleWithStandardLibrary.Marshal(expectedObject)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to convert expected object to byte sequence")
+		}
+
+		return &patch.PatchResult{
+			Patch:    expected,
+			Current:  expected,
+			Modified: expected,
+			Original: nil,
+			Patched:  expectedObject,
+		}, nil
+	}
+
+	return patch.DefaultPatchMaker.Calculate(actualObject, expectedObject, originalObject)
+}
+
+// savedObjectPayload return the body expected by Kibana API to create or update saved object
+func savedObjectPayload(savedObject *SavedObject) map[string]interface{} {
+	payload := map[string]interface{}{
+		"attributes": savedObject.Attributes,
+	}
+	if savedObject.References != nil {
+		payload["references"] = savedObject.References
+	}
+
+	return payload
+}
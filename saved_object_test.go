@@ -0,0 +1,235 @@
+package kbhandler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var urlSavedObject = fmt.Sprintf("%s/s/test/api/saved_objects/visualization/test", baseURL)
+
+func (t *KibanaHandlerTestSuite) TestSavedObjectGet() {
+
+	rawSavedObject := `
+{
+	"id": "test",
+	"type": "visualization",
+	"updated_at": "2023-01-01T00:00:00.000Z",
+	"version": "WzEsMV0=",
+	"namespaces": ["test"],
+	"attributes": {
+		"title": "test",
+		"visState": "{}"
+	},
+	"references": [
+		{
+			"id": "logs-*",
+			"type": "index-pattern",
+			"name": "kibanaSavedObjectMeta.searchSourceJSON.index"
+		}
+	]
+}
+	`
+
+	savedObjectTest := &SavedObject{
+		ID:   "test",
+		Type: "visualization",
+		Attributes: map[string]interface{}{
+			"title":    "test",
+			"visState": "{}",
+		},
+		References: []SavedObjectReference{
+			{
+				ID:   "logs-*",
+				Type: "index-pattern",
+				Name: "kibanaSavedObjectMeta.searchSourceJSON.index",
+			},
+		},
+	}
+
+	httpmock.RegisterResponder("GET", urlSavedObject, func(req *http.Request) (*http.Response, error) {
+		resp := httpmock.NewStringResponse(200, rawSavedObject)
+		return resp, nil
+	})
+
+	savedObject, err := t.kbHandler.SavedObjectGet("visualization", "test", "test")
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Empty(t.T(), cmp.Diff(savedObjectTest, savedObject))
+
+	// When not found
+	httpmock.RegisterResponder("GET", urlSavedObject, httpmock.NewStringResponder(404, `{}`))
+	savedObject, err = t.kbHandler.SavedObjectGet("visualization", "test", "test")
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Nil(t.T(), savedObject)
+
+	// When error
+	httpmock.RegisterResponder("GET", urlSavedObject, httpmock.NewErrorResponder(errors.New("fack error")))
+	_, err = t.kbHandler.SavedObjectGet("visualization", "test", "test")
+	assert.Error(t.T(), err)
+}
+
+func (t *KibanaHandlerTestSuite) TestSavedObjectDelete() {
+
+	httpmock.RegisterResponder("DELETE", urlSavedObject, func(req *http.Request) (*http.Response, error) {
+		resp := httpmock.NewStringResponse(200, "{}")
+		return resp, nil
+	})
+
+	err := t.kbHandler.SavedObjectDelete("visualization", "test", "test")
+	if err != nil {
+		t.Fail(err.Error())
+	}
+
+	// When error
+	httpmock.RegisterResponder("DELETE", urlSavedObject, httpmock.NewErrorResponder(errors.New("fack error")))
+	err = t.kbHandler.SavedObjectDelete("visualization", "test", "test")
+	assert.Error(t.T(), err)
+}
+
+func (t *KibanaHandlerTestSuite) TestSavedObjectCreate() {
+
+	savedObject := &SavedObject{
+		ID:   "test",
+		Type: "visualization",
+		Attributes: map[string]interface{}{
+			"title": "test",
+		},
+	}
+
+	httpmock.RegisterResponder("POST", urlSavedObject, func(req *http.Request) (*http.Response, error) {
+		payload := map[string]interface{}{}
+		if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+			return nil, err
+		}
+		if _, ok := payload["attributes"]; !ok {
+			return httpmock.NewStringResponse(400, `{}`), nil
+		}
+		return httpmock.NewStringResponse(200, `{"id": "test", "type": "visualization", "attributes": {"title": "test"}}`), nil
+	})
+
+	err := t.kbHandler.SavedObjectCreate(savedObject, "test")
+	if err != nil {
+		t.Fail(err.Error())
+	}
+
+	// When error
+	httpmock.RegisterResponder("POST", urlSavedObject, httpmock.NewErrorResponder(errors.New("fack error")))
+	err = t.kbHandler.SavedObjectCreate(savedObject, "test")
+	assert.Error(t.T(), err)
+}
+
+func (t *KibanaHandlerTestSuite) TestSavedObjectUpdate() {
+
+	savedObject := &SavedObject{
+		ID:   "test",
+		Type: "visualization",
+		Attributes: map[string]interface{}{
+			"title": "test",
+		},
+	}
+
+	httpmock.RegisterResponder("PUT", urlSavedObject, func(req *http.Request) (*http.Response, error) {
+		resp := httpmock.NewStringResponse(200, `{"id": "test", "type": "visualization", "attributes": {"title": "test"}}`)
+		return resp, nil
+	})
+
+	err := t.kbHandler.SavedObjectUpdate(savedObject, "test")
+	if err != nil {
+		t.Fail(err.Error())
+	}
+
+	// When error
+	httpmock.RegisterResponder("PUT", urlSavedObject, httpmock.NewErrorResponder(errors.New("fack error")))
+	err = t.kbHandler.SavedObjectUpdate(savedObject, "test")
+	assert.Error(t.T(), err)
+}
+
+func (t *KibanaHandlerTestSuite) TestSavedObjectDiff() {
+	var actual, expected, original *SavedObject
+
+	expected = &SavedObject{
+		ID:   "test",
+		Type: "visualization",
+		Attributes: map[string]interface{}{
+			"title": "test",
+		},
+	}
+
+	// When saved object not exist yet
+	actual = nil
+	diff, err := t.kbHandler.SavedObjectDiff(actual, expected, nil)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.False(t.T(), diff.IsEmpty())
+	assert.Equal(t.T(), expected, diff.Patched)
+
+	// When saved object is the same
+	actual = &SavedObject{
+		ID:   "test",
+		Type: "visualization",
+		Attributes: map[string]interface{}{
+			"title": "test",
+		},
+	}
+	diff, err = t.kbHandler.SavedObjectDiff(actual, expected, actual)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.True(t.T(), diff.IsEmpty())
+	assert.Equal(t.T(), expected, diff.Patched)
+
+	// When saved object is not the same
+	expected = &SavedObject{
+		ID:   "test",
+		Type: "visualization",
+		Attributes: map[string]interface{}{
+			"title": "test2",
+		},
+	}
+	diff, err = t.kbHandler.SavedObjectDiff(actual, expected, actual)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.False(t.T(), diff.IsEmpty())
+	assert.Equal(t.T(), expected, diff.Patched)
+
+	// When kibana add default values
+	actual = &SavedObject{
+		ID:   "test",
+		Type: "visualization",
+		Attributes: map[string]interface{}{
+			"title":       "test",
+			"description": "",
+		},
+	}
+	expected = &SavedObject{
+		ID:   "test",
+		Type: "visualization",
+		Attributes: map[string]interface{}{
+			"title": "test",
+		},
+	}
+	original = &SavedObject{
+		ID:   "test",
+		Type: "visualization",
+		Attributes: map[string]interface{}{
+			"title": "test",
+		},
+	}
+	diff, err = t.kbHandler.SavedObjectDiff(actual, expected, original)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.True(t.T(), diff.IsEmpty())
+	assert.Equal(t.T(), actual, diff.Patched)
+}
@@ -32,8 +32,9 @@ func (t *KibanaHandlerTestSuite) SetupTest() {
 	client.Client.SetTransport(httpmock.DefaultTransport)
 
 	t.kbHandler = &KibanaHandlerImpl{
-		client: client,
-		log:    logrus.NewEntry(logrus.New()),
+		client:   client,
+		log:      NewLogrusLogger(logrus.NewEntry(logrus.New())),
+		registry: &privilegeRegistry{},
 	}
 
 	httpmock.Activate()
@@ -0,0 +1,120 @@
+package kbhandler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation name of spans created by handler
+const tracerName = "github.com/disaster37/kb-handler"
+
+// WithTracing permit to create an OpenTelemetry span for each handler call and for each request sent to Kibana, and to propagate the trace context on its headers
+// The spans of requests are children of the span of handler call, that is child of the span read from the context provided by WithContext
+// When tracerProvider or propagator is nil, the global one is used
+func WithTracing(tracerProvider trace.TracerProvider, propagator propagation.TextMapPropagator) Option {
+	return func(h *KibanaHandlerImpl) {
+		if tracerProvider == nil {
+			tracerProvider = otel.GetTracerProvider()
+		}
+		if propagator == nil {
+			propagator = otel.GetTextMapPropagator()
+		}
+		h.tracer = &tracingTransport{
+			tracer:     tracerProvider.Tracer(tracerName),
+			propagator: propagator,
+		}
+	}
+}
+
+// callSpan is the span of handler call
+type callSpan struct {
+	span trace.Span
+}
+
+// startSpan start the span of handler call, and return copy of handler where the requests sent to Kibana are children of this span
+// It return the handler itself when tracing is not enabled
+func (h *KibanaHandlerImpl) startSpan(method, scope, name, userSpace string) (*KibanaHandlerImpl, callSpan) {
+	if h.tracer == nil {
+		return h, callSpan{}
+	}
+
+	ctx := h.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if userSpace == "" {
+		userSpace = "default"
+	}
+	ctx, span := h.tracer.tracer.Start(ctx, fmt.Sprintf("kbhandler.%s", method),
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(
+			attribute.String("kibana.scope", scope),
+			attribute.String("kibana.space", userSpace),
+			attribute.String("kibana.object", name),
+		),
+	)
+
+	return h.WithContext(ctx).(*KibanaHandlerImpl), callSpan{span: span}
+}
+
+// end record the error returned by handler call and end the span
+func (s callSpan) end(err *error) {
+	if s.span == nil {
+		return
+	}
+
+	if err != nil && *err != nil {
+		s.span.RecordError(*err)
+		s.span.SetStatus(codes.Error, (*err).Error())
+	}
+	s.span.End()
+}
+
+// tracingTransport is the HTTP transport that trace the requests
+// The retries of request are on the same span
+type tracingTransport struct {
+	next       http.RoundTripper
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+func (t *tracingTransport) RoundTrip(req *http.Request) (resp *http.Response, err error) {
+	info := parseRequest(req)
+	ctx, span := t.tracer.Start(req.Context(), fmt.Sprintf("kibana.%s.%s", info.scope, info.operation),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("kibana.scope", info.scope),
+			attribute.String("kibana.operation", info.operation),
+			attribute.String("kibana.space", info.space),
+			attribute.String("kibana.object", info.object),
+			attribute.String("http.method", req.Method),
+			attribute.String("http.url", req.URL.String()),
+		),
+	)
+	defer span.End()
+
+	req = req.Clone(ctx)
+	t.propagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err = t.next.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return resp, err
+	}
+
+	span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
+	if resp.StatusCode >= 400 {
+		span.SetStatus(codes.Error, strconv.Itoa(resp.StatusCode))
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,110 @@
+package kbhandler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/disaster37/go-kibana-rest/v8/kbapi"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func (t *KibanaHandlerTestSuite) TestTracing() {
+
+	exporter := tracetest.NewInMemoryExporter()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	handler := *t.kbHandler.(*KibanaHandlerImpl)
+	WithTracing(tracerProvider, propagation.TraceContext{})(&handler)
+	handler.setupTransport()
+
+	ctx, parent := tracerProvider.Tracer("test").Start(context.Background(), "reconcile")
+	tracedHandler := handler.WithContext(ctx)
+
+	var traceparent string
+	httpmock.RegisterResponder("POST", fmt.Sprintf("%s/s/test/api/spaces/_copy_saved_objects", baseURL), func(req *http.Request) (*http.Response, error) {
+		traceparent = req.Header.Get("traceparent")
+		return httpmock.NewStringResponse(200, `{"other": {"success": true, "successCount": 0}}`), nil
+	})
+	httpmock.RegisterResponder("GET", urlrole, httpmock.NewStringResponder(500, `{}`))
+
+	err := tracedHandler.UserSpaceCopyObject("test", &kbapi.KibanaSpaceCopySavedObjectParameter{Spaces: []string{"other"}})
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	_, err = tracedHandler.RoleGet("test")
+	assert.Error(t.T(), err)
+	parent.End()
+
+	spans := exporter.GetSpans()
+	if !assert.Len(t.T(), spans, 5) {
+		return
+	}
+
+	// Copy objects call
+	assert.Equal(t.T(), "kbhandler.UserSpaceCopyObject", spans[1].Name)
+	assert.Equal(t.T(), trace.SpanKindInternal, spans[1].SpanKind)
+	assert.Equal(t.T(), parent.SpanContext().SpanID(), spans[1].Parent.SpanID())
+	assert.Subset(t.T(), spans[1].Attributes, []attribute.KeyValue{
+		attribute.String("kibana.scope", "user_space"),
+		attribute.String("kibana.space", "test"),
+	})
+	assert.Equal(t.T(), codes.Unset, spans[1].Status.Code)
+
+	// Copy objects request
+	assert.Equal(t.T(), "kibana.user_space.copy_saved_objects", spans[0].Name)
+	assert.Equal(t.T(), trace.SpanKindClient, spans[0].SpanKind)
+	assert.Equal(t.T(), spans[1].SpanContext.SpanID(), spans[0].Parent.SpanID())
+	assert.Equal(t.T(), fmt.Sprintf("00-%s-%s-01", spans[0].SpanContext.TraceID(), spans[0].SpanContext.SpanID()), traceparent)
+	assert.Subset(t.T(), spans[0].Attributes, []attribute.KeyValue{
+		attribute.String("kibana.scope", "user_space"),
+		attribute.String("kibana.operation", "copy_saved_objects"),
+		attribute.String("kibana.space", "test"),
+		attribute.String("http.method", "POST"),
+		attribute.Int("http.status_code", 200),
+	})
+	assert.Equal(t.T(), codes.Unset, spans[0].Status.Code)
+
+	// Get role call failed
+	assert.Equal(t.T(), "kbhandler.RoleGet", spans[3].Name)
+	assert.Equal(t.T(), parent.SpanContext().SpanID(), spans[3].Parent.SpanID())
+	assert.Subset(t.T(), spans[3].Attributes, []attribute.KeyValue{
+		attribute.String("kibana.scope", "role"),
+		attribute.String("kibana.object", "test"),
+		attribute.String("kibana.space", "default"),
+	})
+	assert.Equal(t.T(), codes.Error, spans[3].Status.Code)
+
+	// Get role request failed
+	assert.Equal(t.T(), "kibana.role.get", spans[2].Name)
+	assert.Equal(t.T(), spans[3].SpanContext.SpanID(), spans[2].Parent.SpanID())
+	assert.Subset(t.T(), spans[2].Attributes, []attribute.KeyValue{
+		attribute.String("kibana.scope", "role"),
+		attribute.String("kibana.object", "test"),
+		attribute.String("kibana.space", "default"),
+		attribute.Int("http.status_code", 500),
+	})
+	assert.Equal(t.T(), codes.Error, spans[2].Status.Code)
+
+	// The call without context is traced on a new trace
+	exporter.Reset()
+	httpmock.RegisterResponder("GET", urlrole, httpmock.NewStringResponder(200, `{"name": "test"}`))
+	_, err = handler.RoleGet("test")
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	spans = exporter.GetSpans()
+	if !assert.Len(t.T(), spans, 2) {
+		return
+	}
+	assert.Equal(t.T(), "kbhandler.RoleGet", spans[1].Name)
+	assert.False(t.T(), spans[1].Parent.IsValid())
+	assert.Equal(t.T(), spans[1].SpanContext.SpanID(), spans[0].Parent.SpanID())
+}
@@ -0,0 +1,133 @@
+package kbhandler
+
+import (
+	"net/http"
+	"strings"
+)
+
+// scopePaths is the scope of Kibana API paths, used by metrics and traces
+// The more specific paths come first. The segments after the path are the object name
+var scopePaths = []struct {
+	prefix string
+	scope  string
+}{
+	{"/api/security/role", "role"},
+	{"/api/security/privileges", "privilege"},
+	{"/api/features", "privilege"},
+	{"/api/spaces/space", "user_space"},
+	{"/api/spaces", "user_space"},
+	{"/api/logstash/pipelines", "logstash_pipeline"},
+	{"/api/logstash/pipeline", "logstash_pipeline"},
+	{"/api/saved_objects/_import", "dashboard"},
+	{"/api/saved_objects/_export", "dashboard"},
+	{"/api/saved_objects", "saved_object"},
+	{"/api/data_views/data_view", "data_view"},
+	{"/api/data_views", "data_view"},
+	{"/api/alerting/rule", "alerting_rule"},
+	{"/api/actions/connector", "connector"},
+	{"/api/fleet/agent_policies", "fleet_agent_policy"},
+	{"/api/fleet/package_policies", "fleet_package_policy"},
+	{"/api/fleet/epm/packages", "fleet_package"},
+}
+
+// requestInfo is the Kibana call done by request
+type requestInfo struct {
+	scope     string
+	operation string
+	space     string
+	object    string
+}
+
+// parseRequest compute the scope, the operation, the user space and the object name of request from its path
+// The operation is the action like _enable, or the CRUD operation computed from HTTP method
+func parseRequest(req *http.Request) (info requestInfo) {
+	path := req.URL.Path
+	info.space = "default"
+	if strings.HasPrefix(path, "/s/") {
+		if i := strings.Index(path[3:], "/"); i >= 0 {
+			info.space = path[3 : 3+i]
+			path = path[3+i:]
+		}
+	}
+
+	info.scope = "other"
+	remaining := ""
+	for _, scopePath := range scopePaths {
+		if path == scopePath.prefix || strings.HasPrefix(path, scopePath.prefix+"/") {
+			info.scope = scopePath.scope
+			remaining = strings.TrimPrefix(path, scopePath.prefix)
+			break
+		}
+	}
+
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, "_") {
+			info.operation = strings.TrimPrefix(segment, "_")
+		}
+	}
+
+	objects := []string{}
+	for _, segment := range strings.Split(remaining, "/") {
+		switch {
+		case segment == "", strings.HasPrefix(segment, "_"):
+		case segment == "delete":
+			info.operation = segment
+		default:
+			objects = append(objects, segment)
+		}
+	}
+	info.object = strings.Join(objects, "/")
+
+	if info.operation == "" {
+		switch req.Method {
+		case http.MethodGet:
+			info.operation = "get"
+		case http.MethodPost:
+			info.operation = "create"
+		case http.MethodPut:
+			info.operation = "update"
+		case http.MethodDelete:
+			info.operation = "delete"
+		default:
+			info.operation = strings.ToLower(req.Method)
+		}
+	}
+
+	return info
+}
+
+// setupTransport wrap the HTTP transport of client with the layers enabled by options
+// The retry is wrapped by the tracing, so each attempt is rate limited and recorded on metrics, and all attempts are on the same span
+func (h *KibanaHandlerImpl) setupTransport() {
+	httpClient := h.client.Client.GetClient()
+	transport := httpClient.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	if h.metrics != nil {
+		transport = &metricsTransport{
+			next:    transport,
+			metrics: h.metrics,
+		}
+	}
+	if h.limiter != nil {
+		h.limiter.next = transport
+		h.limiter.metrics = h.metrics
+		transport = h.limiter
+	}
+	if h.retryPolicy != nil {
+		transport = &retryTransport{
+			next:    transport,
+			policy:  *h.retryPolicy,
+			handler: h,
+			metrics: h.metrics,
+		}
+	}
+	if h.tracer != nil {
+		h.tracer.next = transport
+		transport = h.tracer
+	}
+
+	httpClient.Transport = transport
+}
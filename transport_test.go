@@ -0,0 +1,35 @@
+package kbhandler
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRequest(t *testing.T) {
+	cases := []struct {
+		method   string
+		path     string
+		expected requestInfo
+	}{
+		{http.MethodGet, "/api/security/role/test", requestInfo{scope: "role", operation: "get", space: "default", object: "test"}},
+		{http.MethodGet, "/api/security/role", requestInfo{scope: "role", operation: "get", space: "default"}},
+		{http.MethodGet, "/api/logstash/pipelines", requestInfo{scope: "logstash_pipeline", operation: "get", space: "default"}},
+		{http.MethodPut, "/api/spaces/space/test", requestInfo{scope: "user_space", operation: "update", space: "default", object: "test"}},
+		{http.MethodPost, "/s/test/api/spaces/_copy_saved_objects", requestInfo{scope: "user_space", operation: "copy_saved_objects", space: "test"}},
+		{http.MethodPost, "/s/test/api/alerting/rule/test/_enable", requestInfo{scope: "alerting_rule", operation: "enable", space: "test", object: "test"}},
+		{http.MethodPost, "/api/fleet/agent_policies/delete", requestInfo{scope: "fleet_agent_policy", operation: "delete", space: "default"}},
+		{http.MethodPost, "/s/test/api/saved_objects/_import", requestInfo{scope: "dashboard", operation: "import", space: "test"}},
+		{http.MethodDelete, "/s/test/api/saved_objects/index-pattern/test", requestInfo{scope: "saved_object", operation: "delete", space: "test", object: "index-pattern/test"}},
+		{http.MethodDelete, "/api/unknown", requestInfo{scope: "other", operation: "delete", space: "default"}},
+	}
+
+	for _, c := range cases {
+		req, err := http.NewRequest(c.method, "http://localhost:5601"+c.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, c.expected, parseRequest(req), c.path)
+	}
+}
@@ -1,6 +1,9 @@
 package kbhandler
 
 import (
+	"fmt"
+	"net/http"
+
 	"github.com/disaster37/go-kibana-rest/v8/kbapi"
 	"github.com/disaster37/generic-objectmatcher/patch"
 	jsonIterator "github.com/json-iterator/go"
@@ -8,36 +11,106 @@ import (
 )
 
 // UserSpaceCreate permit to create new user space
+// When ownership is set, it's stamped as tag on user space description
 func (h *KibanaHandlerImpl) UserSpaceCreate(kibanaSpace *kbapi.KibanaSpace) (err error) {
-	h.log.Debugf("Create user space %s", kibanaSpace.Name)
+	h.log.Debug("Create user space", "scope", "user_space", "name", kibanaSpace.Name)
+	h, span := h.startSpan("UserSpaceCreate", "user_space", kibanaSpace.Name, "")
+	defer span.end(&err)
+
+	if h.ownership != nil {
+		kibanaSpace = h.stampUserSpace(kibanaSpace)
+	}
+
+	if h.isDryRun() {
+		return h.record(http.MethodPost, "/api/spaces/space", kibanaSpace)
+	}
 
 	_, err = h.client.KibanaSpaces.Create(kibanaSpace)
-	return err
+	return wrapError(err)
 }
 
 // UserSpaceUpdate permit to update user space
 func (h *KibanaHandlerImpl) UserSpaceUpdate(kibanaSpace *kbapi.KibanaSpace) (err error) {
-	h.log.Debugf("Update user space %s", kibanaSpace.Name)
+	h.log.Debug("Update user space", "scope", "user_space", "name", kibanaSpace.Name)
+	h, span := h.startSpan("UserSpaceUpdate", "user_space", kibanaSpace.Name, "")
+	defer span.end(&err)
+
+	if h.ownership != nil {
+		if err = h.checkUserSpaceOwner(kibanaSpace.ID); err != nil {
+			return err
+		}
+		kibanaSpace = h.stampUserSpace(kibanaSpace)
+	}
+
+	if h.isDryRun() {
+		return h.record(http.MethodPut, fmt.Sprintf("/api/spaces/space/%s", kibanaSpace.ID), kibanaSpace)
+	}
 
 	_, err = h.client.KibanaSpaces.Update(kibanaSpace)
-	return err
+	return wrapError(err)
 }
 
 // UserSpaceDelete permit to delete user space
 func (h *KibanaHandlerImpl) UserSpaceDelete(name string) (err error) {
-	h.log.Debugf("Name: %s", name)
+	h.log.Debug("Delete user space", "scope", "user_space", "name", name)
+	h, span := h.startSpan("UserSpaceDelete", "user_space", name, "")
+	defer span.end(&err)
 
-	return h.client.KibanaSpaces.Delete(name)
+	if h.ownership != nil {
+		if err = h.checkUserSpaceOwner(name); err != nil {
+			return err
+		}
+	}
+
+	if h.isDryRun() {
+		return h.record(http.MethodDelete, fmt.Sprintf("/api/spaces/space/%s", name), nil)
+	}
+
+	return wrapError(h.client.KibanaSpaces.Delete(name))
 }
 
 // UserSpaceGet permit to get user space
+// It return nil if user space not exist
 func (h *KibanaHandlerImpl) UserSpaceGet(name string) (userspace *kbapi.KibanaSpace, err error) {
-	h.log.Debugf("Name: %s", name)
+	h.log.Debug("Get user space", "scope", "user_space", "name", name)
+	h, span := h.startSpan("UserSpaceGet", "user_space", name, "")
+	defer span.end(&err)
+
+	userspace, err = h.client.KibanaSpaces.Get(name)
+	return userspace, wrapError(err)
+}
+
+// UserSpaceList permit to list user spaces
+// The name prefix is applied on user space ID
+func (h *KibanaHandlerImpl) UserSpaceList(opts *ListOptions) (userspaces kbapi.KibanaSpaces, err error) {
+	h.log.Debug("List user spaces", "scope", "user_space")
+	h, span := h.startSpan("UserSpaceList", "user_space", "", "")
+	defer span.end(&err)
 
-	return h.client.KibanaSpaces.Get(name)
+	allUserSpaces, err := h.client.KibanaSpaces.List()
+	if err != nil {
+		return nil, wrapError(err)
+	}
+
+	userspaces = make(kbapi.KibanaSpaces, 0, len(allUserSpaces))
+	for _, userspace := range allUserSpaces {
+		if opts.matchName(userspace.ID) && opts.matchReserved(userspace.Reserved) {
+			userspaces = append(userspaces, userspace)
+		}
+	}
+
+	return userspaces, nil
 }
 
+// UserSpaceDiff permit to diff user space
+// The diff rules of user spaces are applied before compute the diff
 func (h *KibanaHandlerImpl) UserSpaceDiff(actualObject, expectedObject, originalObject *kbapi.KibanaSpace) (patchResult *patch.PatchResult, err error) {
+	defer h.observeDiff("user_space", actualObject == nil, &patchResult)
+
+	if h.ownership != nil {
+		expectedObject = h.stampUserSpace(expectedObject)
+	}
+
 	// If not yet exist
 	if actualObject == nil {
 		expected, err := jsonIterator.ConfigCompatibleWithStandardLibrary.Marshal(expectedObject)
@@ -54,11 +127,38 @@ func (h *KibanaHandlerImpl) UserSpaceDiff(actualObject, expectedObject, original
 		}, nil
 	}
 
-	return patch.DefaultPatchMaker.Calculate(actualObject, expectedObject, originalObject)
+	return h.calculate(DiffKindUserSpace, actualObject, expectedObject, originalObject)
 }
 
 func (h *KibanaHandlerImpl) UserSpaceCopyObject(userSpaceOrigin string, copySpec *kbapi.KibanaSpaceCopySavedObjectParameter) (err error) {
-	h.log.Debugf("From User space: %s", userSpaceOrigin)
+	h.log.Debug("Copy objects from user space", "scope", "user_space", "space", userSpaceOrigin)
+	h, span := h.startSpan("UserSpaceCopyObject", "user_space", "", userSpaceOrigin)
+	defer span.end(&err)
+
+	if h.isDryRun() {
+		return h.record(http.MethodPost, userSpacePath(userSpaceOrigin, "/api/spaces/_copy_saved_objects"), copySpec)
+	}
+
+	return wrapError(h.client.KibanaSpaces.CopySavedObjects(copySpec, userSpaceOrigin))
+}
+
+// stampUserSpace return a copy of user space with the ownership tag on description
+func (h *KibanaHandlerImpl) stampUserSpace(kibanaSpace *kbapi.KibanaSpace) *kbapi.KibanaSpace {
+	stamped := *kibanaSpace
+	stamped.Description = h.ownership.stampDescription(kibanaSpace.Description)
+
+	return &stamped
+}
+
+// checkUserSpaceOwner return an error if user space is owned by another manager
+func (h *KibanaHandlerImpl) checkUserSpaceOwner(name string) (err error) {
+	current, err := h.UserSpaceGet(name)
+	if err != nil {
+		return errors.Wrapf(err, "Error when get user space %s", name)
+	}
+	if current == nil {
+		return nil
+	}
 
-	return h.client.KibanaSpaces.CopySavedObjects(copySpec, userSpaceOrigin)
+	return h.checkOwner("user space", name, h.ownership.descriptionOwner(current.Description))
 }
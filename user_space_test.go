@@ -295,3 +295,56 @@ func (t *KibanaHandlerTestSuite) TestUserSpaceCopyObject() {
 	err = t.kbHandler.UserSpaceCopyObject("default", copySpec)
 	assert.Error(t.T(), err)
 }
+
+func (t *KibanaHandlerTestSuite) TestUserSpaceList() {
+
+	url := fmt.Sprintf("%s/api/spaces/space", baseURL)
+
+	rawUserSpaces := `
+[
+	{
+		"id": "default",
+		"name": "Default",
+		"_reserved": true
+	},
+	{
+		"id": "team-a",
+		"name": "Team A"
+	},
+	{
+		"id": "marketing",
+		"name": "Marketing"
+	}
+]
+	`
+
+	httpmock.RegisterResponder("GET", url, httpmock.NewStringResponder(200, rawUserSpaces))
+
+	// Without filter
+	userSpaces, err := t.kbHandler.UserSpaceList(nil)
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Len(t.T(), userSpaces, 3)
+
+	// With name prefix
+	userSpaces, err = t.kbHandler.UserSpaceList(&ListOptions{NamePrefix: "team-"})
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Len(t.T(), userSpaces, 1)
+	assert.Equal(t.T(), "team-a", userSpaces[0].ID)
+
+	// With reserved
+	reserved := false
+	userSpaces, err = t.kbHandler.UserSpaceList(&ListOptions{Reserved: &reserved})
+	if err != nil {
+		t.Fail(err.Error())
+	}
+	assert.Len(t.T(), userSpaces, 2)
+
+	// When error
+	httpmock.RegisterResponder("GET", url, httpmock.NewErrorResponder(errors.New("fack error")))
+	_, err = t.kbHandler.UserSpaceList(nil)
+	assert.Error(t.T(), err)
+}